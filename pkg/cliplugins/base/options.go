@@ -17,10 +17,19 @@ limitations under the License.
 package base
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+
 	"github.com/spf13/cobra"
 
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	"github.com/kcp-dev/kcp/pkg/cliplugins/helpers"
 )
 
 // Options contains options common to most CLI plugins, including settings for connecting to kcp (kubeconfig, etc).
@@ -54,7 +63,8 @@ func (o *Options) BindFlags(cmd *cobra.Command) {
 		return
 	}
 
-	cmd.Flags().StringVar(&o.Kubeconfig, "kubeconfig", o.Kubeconfig, "path to the kubeconfig file")
+	cmd.Flags().StringVar(&o.Kubeconfig, "kubeconfig", o.Kubeconfig, "path to the kubeconfig file, or - to read it from stdin. "+
+		"Several paths can be separated like in KUBECONFIG to merge them, the first file to set a value wins. The other connection flags apply on top.")
 
 	// We add only a subset of kubeconfig-related flags to the plugin.
 	// All those with with LongName == "" will be ignored.
@@ -69,10 +79,32 @@ func (o *Options) BindFlags(cmd *cobra.Command) {
 	clientcmd.BindOverrideFlags(o.KubectlOverrides, cmd.PersistentFlags(), kubectlConfigOverrideFlags)
 }
 
-// Complete initializes ClientConfig based on Kubeconfig and KubectlOverrides.
+// Complete initializes ClientConfig based on Kubeconfig and KubectlOverrides. A Kubeconfig of "-" is read from stdin,
+// and a list of paths is merged like KUBECONFIG is. Either way, Kubeconfig takes precedence over KUBECONFIG, and
+// KubectlOverrides are applied on top of the resulting kubeconfig.
 func (o *Options) Complete() error {
+	if o.Kubeconfig == "-" {
+		if o.In == nil {
+			return errors.New("cannot read kubeconfig from stdin: no input stream")
+		}
+		data, err := io.ReadAll(o.In)
+		if err != nil {
+			return fmt.Errorf("failed to read kubeconfig from stdin: %w", err)
+		}
+		config, err := clientcmd.Load(data)
+		if err != nil {
+			return fmt.Errorf("failed to load kubeconfig from stdin: %w", err)
+		}
+		o.ClientConfig = clientcmd.NewDefaultClientConfig(*config, o.KubectlOverrides)
+		return nil
+	}
+
 	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
-	loadingRules.ExplicitPath = o.Kubeconfig
+	if paths := filepath.SplitList(o.Kubeconfig); len(paths) > 1 {
+		loadingRules.Precedence = paths
+	} else {
+		loadingRules.ExplicitPath = o.Kubeconfig
+	}
 
 	startingConfig, err := loadingRules.GetStartingConfig()
 	if err != nil {
@@ -88,3 +120,50 @@ func (o *Options) Complete() error {
 func (o *Options) Validate() error {
 	return nil
 }
+
+// ClientConfigs returns the rest config of the current workspace, and a copy of it pointing to the kcp server
+// rather than to a workspace, to be used with cluster clients. The copy keeps the proxy of the kubeconfig, and
+// without one both fall back to the proxy environment variables. Both default to the kubernetes user agent. This is
+// only valid after calling Complete.
+func (o *Options) ClientConfigs() (*rest.Config, *rest.Config, error) {
+	config, err := o.ClientConfig.ClientConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(config.UserAgent) == 0 {
+		config.UserAgent = rest.DefaultKubernetesUserAgent()
+	}
+
+	u, _, err := helpers.ParseClusterURL(config.Host)
+	if err != nil {
+		return nil, nil, err
+	}
+	clusterConfig := rest.CopyConfig(config)
+	clusterConfig.Host = u.String()
+
+	return config, clusterConfig, nil
+}
+
+// KcpClients returns a kcp client for the current workspace, and a kcp cluster client to reach any workspace of the
+// same kcp server. This is only valid after calling Complete.
+func (o *Options) KcpClients() (kcpclient.Interface, kcpclient.ClusterInterface, error) {
+	config, clusterConfig, err := o.ClientConfigs()
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewKcpClients(config, clusterConfig)
+}
+
+// NewKcpClients returns a kcp client for the given workspace config, and a kcp cluster client for the given cluster
+// config, as returned by ClientConfigs.
+func NewKcpClients(config, clusterConfig *rest.Config) (kcpclient.Interface, kcpclient.ClusterInterface, error) {
+	client, err := kcpclient.NewForConfig(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create kcp client: %w", err)
+	}
+	clusterClient, err := kcpclient.NewClusterForConfig(clusterConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create kcp cluster client: %w", err)
+	}
+	return client, clusterClient, nil
+}
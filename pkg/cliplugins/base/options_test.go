@@ -0,0 +1,201 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package base
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+)
+
+func TestClientConfigs(t *testing.T) {
+	tests := []struct {
+		name        string
+		server      string
+		clusterHost string
+		wantErr     bool
+	}{
+		{name: "workspace", server: "https://test/clusters/root:foo", clusterHost: "https://test"},
+		{name: "root", server: "https://test:6443/clusters/root", clusterHost: "https://test:6443"},
+		{name: "no workspace", server: "https://test", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			o := &Options{
+				ClientConfig: clientcmd.NewDefaultClientConfig(clientcmdapi.Config{
+					Clusters:       map[string]*clientcmdapi.Cluster{"test": {Server: tt.server}},
+					Contexts:       map[string]*clientcmdapi.Context{"test": {Cluster: "test", AuthInfo: "test"}},
+					AuthInfos:      map[string]*clientcmdapi.AuthInfo{"test": {Token: "token"}},
+					CurrentContext: "test",
+				}, &clientcmd.ConfigOverrides{}),
+			}
+
+			config, clusterConfig, err := o.ClientConfigs()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.server, config.Host)
+			require.Equal(t, tt.clusterHost, clusterConfig.Host)
+			require.Equal(t, "token", clusterConfig.BearerToken)
+		})
+	}
+}
+
+func TestCompleteKubeconfig(t *testing.T) {
+	config := func(server string) clientcmdapi.Config {
+		return clientcmdapi.Config{
+			Clusters:       map[string]*clientcmdapi.Cluster{"test": {Server: server}},
+			Contexts:       map[string]*clientcmdapi.Context{"test": {Cluster: "test"}},
+			CurrentContext: "test",
+		}
+	}
+
+	t.Run("stdin", func(t *testing.T) {
+		data, err := clientcmd.Write(config("https://stdin/clusters/root"))
+		require.NoError(t, err)
+
+		o := NewOptions(genericclioptions.IOStreams{In: bytes.NewReader(data)})
+		o.Kubeconfig = "-"
+		require.NoError(t, o.Complete())
+
+		restConfig, err := o.ClientConfig.ClientConfig()
+		require.NoError(t, err)
+		require.Equal(t, "https://stdin/clusters/root", restConfig.Host)
+	})
+
+	t.Run("merged", func(t *testing.T) {
+		dir := t.TempDir()
+		first, second := filepath.Join(dir, "first"), filepath.Join(dir, "second")
+		require.NoError(t, clientcmd.WriteToFile(clientcmdapi.Config{
+			AuthInfos: map[string]*clientcmdapi.AuthInfo{"test": {Token: "token"}},
+		}, first))
+		secondConfig := config("https://second/clusters/root")
+		secondConfig.Contexts["test"].AuthInfo = "test"
+		require.NoError(t, clientcmd.WriteToFile(secondConfig, second))
+
+		o := NewOptions(genericclioptions.IOStreams{})
+		o.Kubeconfig = strings.Join([]string{first, second}, string(filepath.ListSeparator))
+		require.NoError(t, o.Complete())
+
+		restConfig, err := o.ClientConfig.ClientConfig()
+		require.NoError(t, err)
+		require.Equal(t, "https://second/clusters/root", restConfig.Host)
+		require.Equal(t, "token", restConfig.BearerToken)
+	})
+}
+
+func TestClientConfigsProxy(t *testing.T) {
+	o := &Options{
+		ClientConfig: clientcmd.NewDefaultClientConfig(clientcmdapi.Config{
+			Clusters:       map[string]*clientcmdapi.Cluster{"test": {Server: "https://test/clusters/root:foo", ProxyURL: "http://proxy:3128"}},
+			Contexts:       map[string]*clientcmdapi.Context{"test": {Cluster: "test"}},
+			CurrentContext: "test",
+		}, &clientcmd.ConfigOverrides{}),
+	}
+
+	_, clusterConfig, err := o.ClientConfigs()
+	require.NoError(t, err)
+	require.NotNil(t, clusterConfig.Proxy, "the proxy should survive the host rewrite")
+
+	u, err := url.Parse(clusterConfig.Host)
+	require.NoError(t, err)
+	proxyURL, err := clusterConfig.Proxy(&http.Request{URL: u})
+	require.NoError(t, err)
+	require.Equal(t, "http://proxy:3128", proxyURL.String())
+}
+
+// legacyClusterConfig is the cluster config the bind and claims plugins built themselves before using ClientConfigs.
+func legacyClusterConfig(config *rest.Config) (*rest.Config, error) {
+	clusterConfig := rest.CopyConfig(config)
+	u, err := url.Parse(config.Host)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = ""
+	clusterConfig.Host = u.String()
+	clusterConfig.UserAgent = rest.DefaultKubernetesUserAgent()
+	return clusterConfig, nil
+}
+
+func TestClientConfigsMatchLegacyClusterConfig(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path+" "+r.UserAgent())
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"apiVersion":"apis.kcp.dev/v1alpha1","kind":"APIBindingList","items":[]}`))
+	}))
+	defer server.Close()
+
+	for _, host := range []string{"https://test/clusters/root:foo", "https://test:6443/clusters/root", server.URL + "/clusters/root:foo"} {
+		o := &Options{
+			ClientConfig: clientcmd.NewDefaultClientConfig(clientcmdapi.Config{
+				Clusters:       map[string]*clientcmdapi.Cluster{"test": {Server: host}},
+				Contexts:       map[string]*clientcmdapi.Context{"test": {Cluster: "test"}},
+				CurrentContext: "test",
+			}, &clientcmd.ConfigOverrides{}),
+		}
+		kubeconfigConfig, err := o.ClientConfig.ClientConfig()
+		require.NoError(t, err)
+		legacy, err := legacyClusterConfig(kubeconfigConfig)
+		require.NoError(t, err)
+
+		_, clusterConfig, err := o.ClientConfigs()
+		require.NoError(t, err)
+		require.Equal(t, legacy, clusterConfig, "server %s", host)
+	}
+
+	// both cluster clients send the same requests to the current workspace.
+	_, clusterClient, err := (&Options{
+		ClientConfig: clientcmd.NewDefaultClientConfig(clientcmdapi.Config{
+			Clusters:       map[string]*clientcmdapi.Cluster{"test": {Server: server.URL + "/clusters/root:foo"}},
+			Contexts:       map[string]*clientcmdapi.Context{"test": {Cluster: "test"}},
+			CurrentContext: "test",
+		}, &clientcmd.ConfigOverrides{}),
+	}).KcpClients()
+	require.NoError(t, err)
+	legacy, err := legacyClusterConfig(&rest.Config{Host: server.URL + "/clusters/root:foo"})
+	require.NoError(t, err)
+	legacyClient, err := kcpclient.NewClusterForConfig(legacy)
+	require.NoError(t, err)
+
+	for _, client := range []kcpclient.ClusterInterface{legacyClient, clusterClient} {
+		_, err := client.Cluster(logicalcluster.New("root:foo")).ApisV1alpha1().APIBindings().List(context.Background(), metav1.ListOptions{})
+		require.NoError(t, err)
+	}
+	require.Len(t, requests, 2)
+	require.Equal(t, requests[0], requests[1])
+	require.Equal(t, "/clusters/root:foo/apis/apis.kcp.dev/v1alpha1/apibindings "+rest.DefaultKubernetesUserAgent(), requests[0])
+}
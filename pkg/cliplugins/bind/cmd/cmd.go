@@ -24,6 +24,7 @@ import (
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 
 	"github.com/kcp-dev/kcp/pkg/cliplugins/bind/plugin"
+	"github.com/kcp-dev/kcp/pkg/cliplugins/bind/version"
 )
 
 var (
@@ -41,6 +42,62 @@ var (
 
     # Create a placement to deploy standard kubernetes workloads to synctargets in the "root:mylocations" location workspace, and select only locations in the us-east region.
     %[1]s bind compute root:mylocations --location-selectors=region=us-east1
+
+    # Create a placement to deploy standard kubernetes workloads to synctargets in the "locations" workspace, a child of the current workspace.
+    %[1]s bind compute locations
+
+    # Print the locations in the "root:mylocations" location workspace matched by the given location selectors, without creating anything.
+    %[1]s bind compute root:mylocations --location-selectors=region=us-east1 --selector-preview
+
+    # Create a placement to deploy custom workloads to the synctargets supporting them, in a workspace next to the current one.
+    %[1]s bind compute --discover-location --apiexports=root:myapis:customapiexport
+
+    # Take --apiexports, --location-selectors and --timeout from KCP_BIND_APIEXPORTS, KCP_BIND_LOCATION_SELECTORS and KCP_BIND_TIMEOUT, e.g. in CI pipelines.
+    KCP_BIND_APIEXPORTS=root:myapis:customapiexport KCP_BIND_TIMEOUT=2m %[1]s bind compute root:mylocations
+
+    # Create a placement for namespaces labeled kcp.dev/workload=true, and label the existing default namespace so that it is scheduled right away.
+    %[1]s bind compute root:mylocations --namespace-selector=kcp.dev/workload=true --label-matching-namespaces=default --yes
+
+    # Bind the workspaces listed in binds.yaml, four at a time, and print the result of each of them.
+    %[1]s bind compute --batch=binds.yaml --batch-concurrency=4
+
+    # Bind to every accessible workspace with synctargets supporting the given APIExport, with a placement in each.
+    %[1]s bind compute '*' --apiexports=root:myapis:customapiexport --yes
+
+    # Bind without a kubeconfig, authenticating with a token to the workspace given by --server.
+    %[1]s bind compute root:mylocations --server=https://kcp.example.com:6443/clusters/root:org --token="${TOKEN}" --certificate-authority=ca.crt
+	`
+
+	bindComputeValidateExampleUses = `
+    # Check that standard kubernetes workloads can be bound to synctargets in the "root:mylocations" location workspace, without creating anything.
+    %[1]s bind compute validate root:mylocations
+
+    # Check that the given APIExport and location selector can be used with the "root:mylocations" location workspace.
+    %[1]s bind compute validate root:mylocations --apiexports=root:myapis:customapiexport --location-selectors=region=us-east1
+	`
+
+	bindComputeReconcileExampleUses = `
+    # Keep the APIBindings of the current workspace in sync with the APIExports supported by the synctargets in the "root:mylocations" location workspace.
+    %[1]s bind compute reconcile root:mylocations
+
+    # Also delete the APIBindings of APIExports no longer supported, and reconcile at least every minute.
+    %[1]s bind compute reconcile root:mylocations --prune --resync-interval=1m
+	`
+
+	bindComputeExplainExampleUses = `
+    # Print the resources offered by the "kubernetes" APIExport in the "root:compute" workspace.
+    %[1]s bind compute explain root:compute:kubernetes
+
+    # Also print which synctargets in the "root:mylocations" location workspace support it.
+    %[1]s bind compute explain root:compute:kubernetes --location-workspace=root:mylocations
+	`
+
+	bindComputeDeleteExampleUses = `
+    # Delete the placement "placement-1a2b3c4d" in the current workspace.
+    %[1]s bind compute delete placement-1a2b3c4d
+
+    # Delete all placements linked to the "root:mylocations" location workspace, without asking for confirmation.
+    %[1]s bind compute delete --all --location-workspace=root:mylocations --yes
 	`
 )
 
@@ -79,7 +136,7 @@ func New(streams genericclioptions.IOStreams) *cobra.Command {
 
 	bindComputeOpts := plugin.NewBindComputeOptions(streams)
 	bindComputeCmd := &cobra.Command{
-		Use:          "compute <location workspace>",
+		Use:          "compute [<location workspace>]",
 		Short:        "Bind to a location workspace",
 		Example:      fmt.Sprintf(bindComputeExampleUses, "kubectl kcp"),
 		SilenceUsage: true,
@@ -92,11 +149,111 @@ func New(streams genericclioptions.IOStreams) *cobra.Command {
 				return err
 			}
 
-			return bindComputeOpts.Run(cmd.Context())
+			return bindComputeOpts.RunWithRetries(cmd.Context())
 		},
 	}
 	bindComputeOpts.BindFlags(bindComputeCmd)
 
+	bindComputeValidateOpts := plugin.NewBindComputeOptions(streams)
+	bindComputeValidateCmd := &cobra.Command{
+		Use:          "validate <location workspace>",
+		Short:        "Check that compute can be bound to a location workspace without creating anything",
+		Example:      fmt.Sprintf(bindComputeValidateExampleUses, "kubectl kcp"),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := bindComputeValidateOpts.CompleteValidate(args); err != nil {
+				return err
+			}
+
+			if err := bindComputeValidateOpts.Validate(); err != nil {
+				return err
+			}
+
+			return bindComputeValidateOpts.RunValidate(cmd.Context())
+		},
+	}
+	bindComputeValidateOpts.BindValidateFlags(bindComputeValidateCmd)
+
+	bindComputeCmd.AddCommand(bindComputeValidateCmd)
+
+	bindComputeReconcileOpts := plugin.NewBindComputeOptions(streams)
+	// reconcile always binds every APIExport supported by the synctargets.
+	bindComputeReconcileOpts.Refresh = true
+	bindComputeReconcileCmd := &cobra.Command{
+		Use:          "reconcile <location workspace>",
+		Short:        "Continuously keep the APIBindings in sync with the APIExports supported by a location workspace",
+		Example:      fmt.Sprintf(bindComputeReconcileExampleUses, "kubectl kcp"),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := bindComputeReconcileOpts.Complete(args); err != nil {
+				return err
+			}
+
+			if err := bindComputeReconcileOpts.Validate(); err != nil {
+				return err
+			}
+
+			return bindComputeReconcileOpts.RunReconcile(cmd.Context())
+		},
+	}
+	bindComputeReconcileOpts.BindReconcileFlags(bindComputeReconcileCmd)
+
+	bindComputeCmd.AddCommand(bindComputeReconcileCmd)
+
+	bindComputeExplainOpts := plugin.NewBindComputeExplainOptions(streams)
+	bindComputeExplainCmd := &cobra.Command{
+		Use:          "explain <workspace_path:apiexport-name>",
+		Short:        "Print the resources offered by an APIExport and the synctargets supporting it",
+		Example:      fmt.Sprintf(bindComputeExplainExampleUses, "kubectl kcp"),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := bindComputeExplainOpts.Complete(args); err != nil {
+				return err
+			}
+
+			if err := bindComputeExplainOpts.Validate(); err != nil {
+				return err
+			}
+
+			return bindComputeExplainOpts.Run(cmd.Context())
+		},
+	}
+	bindComputeExplainOpts.BindFlags(bindComputeExplainCmd)
+
+	bindComputeCmd.AddCommand(bindComputeExplainCmd)
+
+	bindComputeDeleteOpts := plugin.NewBindComputeDeleteOptions(streams)
+	bindComputeDeleteCmd := &cobra.Command{
+		Use:          "delete [<placement name>...] [--all]",
+		Short:        "Delete placements in the current workspace",
+		Example:      fmt.Sprintf(bindComputeDeleteExampleUses, "kubectl kcp"),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := bindComputeDeleteOpts.Complete(args); err != nil {
+				return err
+			}
+
+			if err := bindComputeDeleteOpts.Validate(); err != nil {
+				return err
+			}
+
+			return bindComputeDeleteOpts.Run(cmd.Context())
+		},
+	}
+	bindComputeDeleteOpts.BindFlags(bindComputeDeleteCmd)
+
+	bindComputeCmd.AddCommand(bindComputeDeleteCmd)
 	cmd.AddCommand(bindComputeCmd)
+
+	versionCmd := &cobra.Command{
+		Use:          "version",
+		Short:        "Print the version of the bind plugin and the kcp APIs it is compatible with",
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return version.Get().Print(streams.Out)
+		},
+	}
+	cmd.AddCommand(versionCmd)
 	return cmd
 }
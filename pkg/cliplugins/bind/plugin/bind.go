@@ -20,7 +20,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net/url"
 	"strings"
 	"time"
 
@@ -30,10 +29,8 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
-	"k8s.io/client-go/rest"
 
 	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
-	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
 	"github.com/kcp-dev/kcp/pkg/cliplugins/base"
 	pluginhelpers "github.com/kcp-dev/kcp/pkg/cliplugins/helpers"
 )
@@ -124,7 +121,7 @@ func (b *BindOptions) Run(ctx context.Context) error {
 		},
 	}
 
-	kcpclient, err := newKCPClusterClient(config)
+	_, kcpclient, err := b.KcpClients()
 	if err != nil {
 		return err
 	}
@@ -160,15 +157,3 @@ func (b *BindOptions) Run(ctx context.Context) error {
 
 	return nil
 }
-
-func newKCPClusterClient(config *rest.Config) (kcpclient.ClusterInterface, error) {
-	clusterConfig := rest.CopyConfig(config)
-	u, err := url.Parse(config.Host)
-	if err != nil {
-		return nil, err
-	}
-	u.Path = ""
-	clusterConfig.Host = u.String()
-	clusterConfig.UserAgent = rest.DefaultKubernetesUserAgent()
-	return kcpclient.NewClusterForConfig(clusterConfig)
-}
@@ -19,30 +19,63 @@ package plugin
 import (
 	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"net"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/kcp-dev/logicalcluster/v2"
 	"github.com/martinlindhe/base36"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
-	"k8s.io/apimachinery/pkg/api/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
-	"k8s.io/client-go/rest"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/component-base/version"
 
 	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
 	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
 	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
 	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
 	"github.com/kcp-dev/kcp/pkg/cliplugins/base"
-	"github.com/kcp-dev/kcp/pkg/cliplugins/helpers"
+)
+
+const (
+	// serverSideApplyFieldManager is the default field manager owning the fields applied with --server-side-apply.
+	serverSideApplyFieldManager = "kcp-bind-compute"
+	// maxFieldManagerLength is the longest field manager accepted by the API server.
+	maxFieldManagerLength = 128
+
+	// SourceVersionAnnotationKey records the version of bind compute that created an object, with --annotate-with-source.
+	SourceVersionAnnotationKey = "bind.kcp.dev/source-version"
+
+	// SourceUserAnnotationKey records the user who ran bind compute to create an object, with --annotate-with-source.
+	SourceUserAnnotationKey = "bind.kcp.dev/source-user"
+
+	// IdempotencyKeyAnnotationKey records the key derived from the inputs a placement was created from, to tell a
+	// retried create that already succeeded from a placement created by someone else.
+	IdempotencyKeyAnnotationKey = "bind.kcp.dev/idempotency-key"
 )
 
 type BindComputeOptions struct {
@@ -50,160 +83,1322 @@ type BindComputeOptions struct {
 
 	// PlacementName is the name of the placement
 	PlacementName string
+	// placementNameDefaulted is set when the placement is named after its selectors, rather than with --name.
+	placementNameDefaulted bool
+
+	// Placements are additional placements to create instead of the single one, sharing the APIBindings and the
+	// location workspace, as name=<name>,location-selectors=<selector>,namespace-selector=<selector> tuples.
+	Placements []string
+	placements []placementOptions
+
+	// SelectorsFrom is the name of an existing placement whose namespace and location selectors are used for the
+	// placement to create, unless given by flags.
+	SelectorsFrom string
+
+	// APIExports is a list of APIExport to use in the workspace.
+	APIExports []string
+
+	// ExcludeAPIExports are APIExports removed from those to bind, e.g. from those bound with --refresh.
+	ExcludeAPIExports []string
+
+	// IgnoreMissingExcludes ignores ExcludeAPIExports that are not among the APIExports to bind, instead of failing.
+	IgnoreMissingExcludes bool
+
+	// APIExportsFromWorkspace is the path of a workspace whose APIExports are all bound, in addition to APIExports,
+	// as long as the SyncTargets support them.
+	APIExportsFromWorkspace string
+	apiExportsFromWorkspace logicalcluster.Name
+	workspaceAPIExports     sets.String
+
+	// Namespace selector is a label selector to select namespace for the workload.
+	namespaceSelector       *metav1.LabelSelector
+	NamespaceSelectorString string
+	// namespaceSelectorString is the namespace selector in effect, after --all-namespaces, --namespace-preset and
+	// --namespace-match-expression.
+	namespaceSelectorString string
+
+	// NamespaceMatchExpressions are requirements appended to the namespace selector, as key,operator[,value...].
+	NamespaceMatchExpressions []string
+
+	// NamespacePreset is the name of a predefined namespace selector, used unless NamespaceSelectorString is set.
+	NamespacePreset string
+
+	// LocationSelectors is a list of label selectors to select locations in the location workspace.
+	locationSelectors        []metav1.LabelSelector
+	LocationSelectorsStrings []string
+
+	// LocationMatchLabels are label sets, as key=value pairs separated by commas, each appended to the location
+	// selectors as a selector matching these labels.
+	LocationMatchLabels []string
+
+	// KubernetesVersion selects the kubernetes-<version> APIExport bound by default instead of the kubernetes one,
+	// when no APIExports are given.
+	KubernetesVersion string
+
+	// SyncTargetSelector is a label selector restricting the SyncTargets whose supported APIExports are bound.
+	SyncTargetSelector string
+
+	// FromSyncTarget is the name of the SyncTarget whose supported APIExports are bound, instead of those of all
+	// SyncTargets in the location workspace.
+	FromSyncTarget string
+
+	// AllNamespaces selects all namespaces for the workload. It is mutually exclusive with NamespaceSelectorString.
+	AllNamespaces bool
+
+	// AllLocations selects all locations in the location workspace. It is mutually exclusive with LocationSelectorsStrings.
+	AllLocations bool
+
+	// LocationResource is the resource the placement selects locations of, as <resource>.<version>.<group>.
+	LocationResource string
+	locationResource schedulingv1alpha1.GroupVersionResource
+
+	// LocationWorkspace is the workspace for synctarget
+	LocationWorkspace logicalcluster.Name
+
+	// BindWaitTimeout is how long to wait for the placement to be created and successful.
+	BindWaitTimeout time.Duration
+
+	// WaitBindingsOnly only waits for the APIBindings to be bound, not for the placement to be ready.
+	WaitBindingsOnly bool
+
+	// WaitPlacementOnly only waits for the placement to be ready, not for the APIBindings to be bound.
+	WaitPlacementOnly bool
+
+	// NoWaitOnExisting returns without creating anything when all APIBindings and the placement already exist and are
+	// ready.
+	NoWaitOnExisting bool
+
+	// IfNotExists returns without doing anything when the placement already exists, whatever its state and the state of
+	// the APIBindings.
+	IfNotExists bool
+
+	// OutputStatusOnly waits for the existing APIBindings and placement to be ready and reports their status, without
+	// creating anything, like kubectl wait.
+	OutputStatusOnly bool
+
+	// RequiredConditions are condition types that must be True on the placement, in addition to Ready, for it to be
+	// considered ready.
+	RequiredConditions []string
+
+	// ExclusiveLocations checks that the placement does not select locations already selected by other placements
+	// in the workspace.
+	ExclusiveLocations bool
+
+	// Strict turns warnings about overlapping placements into errors.
+	Strict bool
+
+	// ShowSelectedLocations prints the locations selected by the placement once it is ready.
+	ShowSelectedLocations bool
+
+	// UserAgent is the user agent set on the kcp clients. It defaults to kcp-bind-compute/<version>.
+	UserAgent string
+
+	// QPS is the refill rate for the kcp clients' rate limiter bucket (steady state requests per second).
+	QPS float32
+
+	// Burst is the maximum size for the kcp clients' rate limiter bucket when idle.
+	Burst int
+
+	// DirectURL is the URL the cluster client reaches workspaces under, e.g. a virtual workspace URL, instead of the kcp
+	// server derived from the current workspace URL.
+	DirectURL string
+
+	// Preflight checks that both the current workspace and the location workspace can be reached before doing anything.
+	Preflight bool
+
+	// SelectorPreview prints the locations matched by the location selectors and exits without creating anything.
+	SelectorPreview bool
+
+	// LabelMatchingNamespaces are namespaces the match labels of the namespace selector are applied to once the
+	// placement is created, so that they match it right away.
+	LabelMatchingNamespaces []string
+
+	// LabelAllNamespaces applies the match labels of the namespace selectors to all namespaces of the workspace.
+	LabelAllNamespaces bool
+
+	// Yes skips the confirmation prompt before labeling namespaces or replacing the placement.
+	Yes bool
+
+	// namespaceClient is the client namespaces are labeled with, set by Run when labeling namespaces.
+	namespaceClient kubernetes.Interface
+
+	// SelectorDump prints the parsed namespace and location selectors in their canonical form before binding.
+	SelectorDump bool
+
+	// PrintPlacementSpec prints the resolved placement, including its name and parsed selectors, before it is created.
+	PrintPlacementSpec bool
+
+	// ShowCommands prints the kubectl commands equivalent to the objects being created.
+	ShowCommands bool
+
+	// BindingNameStrategy is the naming scheme of the APIBindings, one of bindingNameStrategies.
+	BindingNameStrategy string
+
+	// ServerSideApply applies the APIBindings and placement with server-side apply instead of creating them, so that
+	// repeated and concurrent runs converge.
+	ServerSideApply bool
+	// FieldManager is the field manager owning the fields applied with server-side apply.
+	FieldManager string
+
+	// Replace deletes and recreates an existing placement of the same name whose spec differs from the requested one.
+	Replace bool
+
+	// Refresh binds every APIExport currently supported by the SyncTargets in the location workspace, in addition to
+	// the requested ones.
+	Refresh bool
+
+	// Prune deletes APIBindings created by bind compute whose APIExport is no longer supported. Requires Refresh.
+	Prune bool
+
+	// ResyncInterval is the interval at which the reconcile subcommand reconciles the APIBindings even if no SyncTarget
+	// changed.
+	ResyncInterval time.Duration
+
+	// Output is the format the created objects are printed in once ready. Valid values are yaml, json and name-vars.
+	Output string
+
+	// OutputFile is the path to a file the created objects are written to instead of stdout.
+	OutputFile string
+
+	// PrintRef prints a reference to each placement, as <workspace path>/placements/<name>, once ready, so that the
+	// bind can be chained into other commands.
+	PrintRef bool
+
+	// ObjectsDir is the path to a directory each object is written to as its own file, once ready.
+	ObjectsDir string
+
+	// IncludeMatchedLocations adds the Locations selected by the placements to the objects printed with --output.
+	IncludeMatchedLocations bool
+
+	// FailureReport is the path to a file a JSON report of the state of the bind is written to when it fails.
+	FailureReport string
+
+	// FailFast stops waiting for readiness as soon as an APIBinding or the placement reports a terminal failure, see
+	// terminalBindingConditions and terminalPlacementReasons.
+	FailFast bool
+
+	// ContinueOnBindingError creates the placement and waits for the APIBindings that were created when others failed
+	// to be created, instead of failing right away. The bind then fails with a PartialBindError.
+	ContinueOnBindingError bool
+
+	// DebugDumpOnFailure is the path to a file the YAML of the placement, the APIBindings, and the SyncTargets and
+	// Locations of the location workspace are written to when the bind fails, for troubleshooting.
+	DebugDumpOnFailure string
+
+	// CallbackURL is a URL the result of the bind is posted to as JSON when it is done, whether it succeeded or not.
+	CallbackURL string
+
+	// VerifyExportEndpoints warns about APIExports without ready virtual workspace endpoints before binding them.
+	VerifyExportEndpoints bool
+
+	// ExpectIdentities are the identity hashes the APIExports must have to be bound, as
+	// <workspace_path>:<apiexport>=<hash>, or just <hash> when binding a single APIExport.
+	ExpectIdentities   []string
+	expectedIdentities map[string]string
+
+	// IgnoreUnsupported skips requested APIExports that are not supported by the SyncTargets instead of failing.
+	IgnoreUnsupported bool
+
+	// PollInterval is the initial interval between readiness checks.
+	PollInterval time.Duration
+
+	// PollFactor is the factor the interval between readiness checks is multiplied by after each check.
+	PollFactor float64
+
+	// PollMaxInterval caps the interval between readiness checks.
+	PollMaxInterval time.Duration
+
+	// PollJitter is the maximum factor of the poll interval randomly added to each wait between readiness checks.
+	PollJitter float64
+
+	// Table prints a table of the APIBindings and the placement along with the summary.
+	Table bool
+
+	// NoHeaders omits the headers of the table.
+	NoHeaders bool
+
+	// Columns are the columns of the table to print, all of them if empty.
+	Columns []string
+
+	// Compact prints a single line per action on the APIBindings, instead of a line per APIBinding.
+	Compact bool
+
+	// Verbose prints the type of the location workspace, and details about the state of each APIBinding and the
+	// placement.
+	Verbose bool
+
+	// Timings prints the time from the start of the bind to the first APIBinding bound, and to the bind being ready.
+	Timings bool
+
+	// Trace logs the method, URL, status and duration of each request to kcp, with credentials redacted.
+	Trace bool
+
+	// Attempts is the number of times the bind is run before giving up on transient failures.
+	Attempts int
+
+	// AttemptDelay is the delay between attempts.
+	AttemptDelay time.Duration
+
+	// deferReport is set while the bind is run by RunWithRetries, which reports the last run only.
+	deferReport bool
+	// lastRun is the state the last run of the bind ended in, until it is reported.
+	lastRun *runState
+
+	// Deadline is the overall time budget for the whole bind, shared by all phases. Zero means no deadline.
+	Deadline time.Duration
+
+	// AcceptPermissionClaims lists the permission claims to accept on the created APIBindings, as
+	// <resource>.<group>, or just <resource> for core resources. "all" accepts every claim offered by the APIExports.
+	AcceptPermissionClaims []string
+
+	// TargetWorkspace is the workspace to create the APIBindings and placement in, instead of the current workspace.
+	TargetWorkspace string
+	targetWorkspace logicalcluster.Name
+
+	// ProtectedWorkspaces are the workspaces bind compute refuses to create APIBindings and placements in, unless
+	// AllowRoot is set.
+	ProtectedWorkspaces []string
+
+	// AllowRoot allows creating APIBindings and placements in the protected workspaces.
+	AllowRoot bool
+
+	// AnnotateWithSource annotates the created objects with the version of bind compute and the user running it.
+	AnnotateWithSource bool
+	annotations        map[string]string
+
+	// OwnerRefs are owner references set on the created APIBindings and placement, as apiVersion/Kind/name/uid.
+	OwnerRefs       []string
+	ownerReferences []metav1.OwnerReference
+
+	// result collects the warnings of the current run, shared with the options of each placement.
+	result *BindComputeResult
+
+	// wildcard is set when the location workspace is given as *, to bind to every workspace found with SyncTargets
+	// supporting the APIExports.
+	wildcard bool
+
+	// DiscoverLocation finds the location workspace among the workspaces around the current one, instead of taking it
+	// as an argument.
+	DiscoverLocation bool
+
+	// Profile is the name of the profile in ProfilesFile whose values are used for the flags not given explicitly.
+	Profile string
+
+	// ProfilesFile is the path to the file defining the profiles. It defaults to ~/.kcp/bind-compute-profiles.yaml.
+	ProfilesFile string
+
+	// Batch is the path to a YAML file listing bind requests to run instead of a single bind.
+	Batch string
+	batch []*BindComputeOptions
+
+	// BatchConcurrency is the number of bind requests of the batch run at the same time.
+	BatchConcurrency int
+
+	// flags is the flagset the options are bound to, to tell the flags given explicitly from the profile values.
+	flags *pflag.FlagSet
+}
+
+// defaultLocationResource is the resource placements select locations of by default.
+var defaultLocationResource = schedulingv1alpha1.GroupVersionResource{
+	Group:    "workload.kcp.dev",
+	Version:  "v1alpha1",
+	Resource: "synctargets",
+}
+
+func NewBindComputeOptions(streams genericclioptions.IOStreams) *BindComputeOptions {
+	return &BindComputeOptions{
+		Options:                 base.NewOptions(streams),
+		LocationResource:        formatLocationResource(defaultLocationResource),
+		locationResource:        defaultLocationResource,
+		NamespaceSelectorString: labels.Everything().String(),
+		LocationSelectorsStrings: []string{
+			labels.Everything().String(),
+		},
+		BindingNameStrategy: "hash",
+		FieldManager:        serverSideApplyFieldManager,
+		Attempts:            1,
+		BatchConcurrency:    1,
+		AttemptDelay:        time.Second * 5,
+		QPS:                 20,
+		Burst:               30,
+		PollInterval:        time.Millisecond * 200,
+		PollFactor:          1.5,
+		PollMaxInterval:     time.Second * 5,
+		PollJitter:          0.1,
+		ResyncInterval:      time.Minute * 5,
+		Table:               true,
+		ProtectedWorkspaces: []string{
+			tenancyv1alpha1.RootCluster.String(),
+		},
+	}
+}
+
+// BindFlags binds fields SyncOptions as command line flags to cmd's flagset.
+func (o *BindComputeOptions) BindFlags(cmd *cobra.Command) {
+	o.Options.BindFlags(cmd)
+	o.bindSelectionFlags(cmd)
+	o.flags = cmd.Flags()
+
+	cmd.Flags().StringVar(&o.PlacementName, "name", o.PlacementName, "Name of the placement to be created.")
+	cmd.Flags().StringArrayVar(&o.Placements, "placement", o.Placements, "Placement to create instead of the single one, as name=<name>,location-selectors=<selector>,namespace-selector=<selector>, "+
+		"where location-selectors can be repeated, and each key is optional. Can be repeated to create several placements sharing the APIBindings.")
+	cmd.Flags().StringVar(&o.SelectorsFrom, "selectors-from", o.SelectorsFrom, "Name of an existing placement to copy the namespace and location selectors from, "+
+		"to clone its binding config. Selector flags given explicitly take precedence.")
+	cmd.Flags().StringSliceVar(&o.ProtectedWorkspaces, "protected-workspaces", o.ProtectedWorkspaces, "Workspaces to refuse creating the APIBindings and placement in, as they are shared.")
+	cmd.Flags().BoolVar(&o.AllowRoot, "allow-root", o.AllowRoot, "Allow creating the APIBindings and placement in the root workspace, or any other of --protected-workspaces.")
+	cmd.Flags().StringVar(&o.Profile, "profile", o.Profile, "Name of a profile in the profiles file to take the APIExports, selectors and timeout from. Flags given explicitly take precedence.")
+	cmd.Flags().StringVar(&o.ProfilesFile, "profiles-file", o.ProfilesFile, "Path to the profiles file used with --profile. Defaults to ~/.kcp/bind-compute-profiles.yaml.")
+	cmd.Flags().BoolVar(&o.DiscoverLocation, "discover-location", o.DiscoverLocation, "Without a location workspace argument, look for the workspace with synctargets supporting the APIExports "+
+		"among the current workspace, its parent, and their children, within --timeout. If several workspaces qualify, asks which one to use on an interactive terminal, and fails otherwise or with --yes.")
+	cmd.Flags().StringVar(&o.APIExportsFromWorkspace, "apiexports-from-workspace", o.APIExportsFromWorkspace, "Absolute path of a workspace, e.g. root:shared, to bind all the APIExports of "+
+		"in addition to --apiexports. Those not supported by any synctarget in the location workspace are skipped with a warning.")
+	cmd.Flags().StringVar(&o.Batch, "batch", o.Batch, "Path to a YAML file listing bind requests to run instead of a single bind, each with a locationWorkspace, and optionally a "+
+		"targetWorkspace, apiExports, namespaceSelector, locationSelectors and placementName defaulting to the flags. A report of the result of each request is printed at the end.")
+	cmd.Flags().IntVar(&o.BatchConcurrency, "batch-concurrency", o.BatchConcurrency, "Number of bind requests of --batch run at the same time.")
+	cmd.Flags().StringVar(&o.TargetWorkspace, "target-workspace", o.TargetWorkspace, "Absolute path of the workspace to create the APIBindings and placement in, e.g. root:org:team. Defaults to the current workspace.")
+	cmd.Flags().Var(newTimeoutValue(time.Second*30, &o.BindWaitTimeout), "timeout", "Duration to wait for Placement to be created and bound successfully. "+
+		"0, never or infinite wait forever. Defaults to $KCP_BIND_TIMEOUT if set.")
+	cmd.Flags().DurationVar(&o.Deadline, "deadline", o.Deadline, "Overall time budget for the bind, including creating objects and waiting for readiness. The wait gets whatever is left of it.")
+	cmd.Flags().IntVar(&o.Attempts, "attempts", o.Attempts, "Number of times to run the whole bind before giving up on transient failures, like network errors or unavailable servers. "+
+		"Invalid options and an expired --timeout or --deadline are never retried.")
+	cmd.Flags().DurationVar(&o.AttemptDelay, "attempt-delay", o.AttemptDelay, "Delay between attempts.")
+	cmd.Flags().DurationVar(&o.PollInterval, "poll-interval", o.PollInterval, "Initial interval between checks of the APIBindings and Placement readiness.")
+	cmd.Flags().Float64Var(&o.PollFactor, "poll-factor", o.PollFactor, "Factor the interval between readiness checks is multiplied by after each check, up to --poll-max-interval.")
+	cmd.Flags().DurationVar(&o.PollMaxInterval, "poll-max-interval", o.PollMaxInterval, "Maximum interval between readiness checks.")
+	cmd.Flags().Float64Var(&o.PollJitter, "poll-jitter", o.PollJitter, "Maximum factor of the poll interval randomly added between readiness checks, to spread the load of concurrent invocations.")
+	cmd.Flags().BoolVar(&o.WaitBindingsOnly, "wait-bindings-only", o.WaitBindingsOnly, "Only wait for the APIBindings to be bound, not for the placement to be ready.")
+	cmd.Flags().BoolVar(&o.WaitPlacementOnly, "wait-placement-only", o.WaitPlacementOnly, "Only wait for the placement to be ready, not for the APIBindings to be bound.")
+	cmd.Flags().BoolVar(&o.OutputStatusOnly, "output-status-only", o.OutputStatusOnly, "Do not create anything, only wait for the existing placement given by --name and the APIBindings "+
+		"of the APIExports to be ready, and report their status. Fails if they are not ready within --timeout.")
+	cmd.Flags().StringSliceVar(&o.RequiredConditions, "require-condition", o.RequiredConditions, "Condition type that must be True on the placement, in addition to Ready, "+
+		"for it to be considered ready, e.g. WorkloadScheduled. Can be repeated.")
+	cmd.Flags().BoolVar(&o.NoWaitOnExisting, "no-wait-on-existing", o.NoWaitOnExisting, "If all the APIBindings and the placement already exist and are ready, report them as already bound "+
+		"and return without any create call.")
+	cmd.Flags().BoolVar(&o.IfNotExists, "if-not-exists", o.IfNotExists, "If the placement already exists, report it as already bound and return right away, "+
+		"without checking its readiness nor the APIBindings, e.g. in provisioning scripts run repeatedly.")
+	cmd.Flags().BoolVar(&o.ExclusiveLocations, "exclusive-locations", o.ExclusiveLocations, "Warn if the placement selects locations that are already selected by other placements in the workspace.")
+	cmd.Flags().BoolVar(&o.Strict, "strict", o.Strict, "Fail instead of warning when --exclusive-locations detects overlapping placements.")
+	cmd.Flags().BoolVar(&o.Verbose, "verbose", o.Verbose, "Print the type of the location workspace, and the state of each APIBinding and the placement at each readiness check, prefixed with the time elapsed waiting.")
+	cmd.Flags().BoolVar(&o.Timings, "timings", o.Timings, "Print the time to the first APIBinding bound and the time to the bind being ready, "+
+		"to tell slow APIExports from slow placement scheduling.")
+	cmd.Flags().BoolVar(&o.Trace, "trace", o.Trace, "Log the method, URL, status and duration of each request to kcp to stderr, with the Authorization header redacted, to debug how requests are routed to the workspaces.")
+	cmd.Flags().BoolVar(&o.Table, "table", o.Table, "Print a table of the APIBindings and the placement, with their status, once done. "+
+		"The table is not printed with -o yaml, json or name-vars, whose output is meant to be parsed.")
+	cmd.Flags().BoolVar(&o.NoHeaders, "no-headers", o.NoHeaders, "Omit the headers of the table.")
+	cmd.Flags().StringSliceVar(&o.Columns, "columns", o.Columns, fmt.Sprintf("Columns of the table to print, out of %s, and %s with -o wide. Defaults to all of them.",
+		strings.Join(tableColumns, ", "), strings.Join(wideTableColumns, ", ")))
+	cmd.Flags().BoolVar(&o.Compact, "compact", o.Compact, "Print a single line listing the APIBindings created, instead of a line per APIBinding.")
+	cmd.Flags().BoolVar(&o.ShowSelectedLocations, "show-selected-locations", o.ShowSelectedLocations, "Print the locations selected by the placement once it is ready.")
+	cmd.Flags().BoolVar(&o.Preflight, "preflight", o.Preflight, "Check that both the current workspace and the location workspace can be reached before creating anything, "+
+		"failing fast with the URL that cannot be reached.")
+	cmd.Flags().BoolVar(&o.SelectorPreview, "selector-preview", o.SelectorPreview, "Print the locations matched by each of the location selectors in the location workspace, and exit without creating anything.")
+	cmd.Flags().StringSliceVar(&o.LabelMatchingNamespaces, "label-matching-namespaces", o.LabelMatchingNamespaces, "Namespaces to apply the labels of the namespace selector to "+
+		"once the placement is created, so that they are scheduled right away. With several --placement, the labels of all their namespace selectors are applied. "+
+		"Only selectors made of key=value labels are supported.")
+	cmd.Flags().BoolVar(&o.LabelAllNamespaces, "label-all-namespaces", o.LabelAllNamespaces, "Apply the labels of the namespace selector to all namespaces of the workspace, like --label-matching-namespaces. "+
+		"Asks for confirmation unless --yes is given.")
+	cmd.Flags().BoolVarP(&o.Yes, "yes", "y", o.Yes, "Label the namespaces, or replace the placement with --replace, without asking for confirmation.")
+	cmd.Flags().BoolVar(&o.SelectorDump, "selector-dump", o.SelectorDump, "Print the namespace selector and each location selector as parsed, in their canonical form, "+
+		"to check that set-based expressions and quoting were understood as intended.")
+	cmd.Flags().BoolVar(&o.PrintPlacementSpec, "print-placement-spec", o.PrintPlacementSpec, "Print the resolved placement as YAML, with its generated name and parsed selectors, before creating it.")
+	cmd.Flags().BoolVar(&o.ShowCommands, "show-commands", o.ShowCommands, "Print the kubectl commands equivalent to the APIBindings and Placement being created.")
+	cmd.Flags().StringVar(&o.BindingNameStrategy, "binding-name-strategy", o.BindingNameStrategy, "Naming scheme of the APIBindings: 'hash' names them <apiexport>-<hash of the workspace path>, "+
+		"'export-name' after the APIExport, and 'export-name-workspace' <apiexport>-<workspace path with dashes>. Names other than hashes are checked not to collide.")
+	cmd.Flags().BoolVar(&o.ServerSideApply, "server-side-apply", o.ServerSideApply, "Apply the APIBindings and placement with server-side apply, as --field-manager, "+
+		"instead of creating them. Repeated and concurrent runs converge, and an existing placement is updated to the requested selectors.")
+	cmd.Flags().StringVar(&o.FieldManager, "field-manager", o.FieldManager, "Name of the field manager owning the fields applied with --server-side-apply, "+
+		"so that several tools managing the same objects can co-own their fields.")
+	cmd.Flags().BoolVar(&o.Replace, "replace", o.Replace, "If a placement of the same name exists with different selectors, delete it, wait for it to be gone, "+
+		"and create it again, for a clean object when its fields cannot be updated. Asks for confirmation unless --yes is given.")
+	cmd.Flags().StringSliceVar(&o.AcceptPermissionClaims, "accept-permission-claims", o.AcceptPermissionClaims,
+		"Permission claims to accept on the created APIBindings, as <resource>.<group>, or <resource> for core resources. Use 'all' to accept every claim offered by the APIExports.")
+	cmd.Flags().BoolVar(&o.Refresh, "refresh", o.Refresh, "Bind every APIExport currently supported by the synctargets in the location workspace.")
+	cmd.Flags().BoolVar(&o.Prune, "prune", o.Prune, "With --refresh, delete APIBindings created by bind compute whose APIExport is no longer supported by the synctargets.")
+	cmd.Flags().StringVarP(&o.Output, "output", "o", o.Output, "Output format for the created objects. Valid values are 'yaml', 'json', 'name-vars' and 'wide'. "+
+		"With 'json', a single JSON object with the placements, the APIBindings, the readiness, the elapsed time, the warnings and the error if any is printed, "+
+		"whether the bind succeeds or not, and progress messages go to stderr. "+
+		"With 'name-vars', PLACEMENT_NAME and BINDING_NAMES shell variables are printed, to be used with eval, and progress messages go to stderr. "+
+		"With 'wide', the table is printed with the AGE, LOCATION-WORKSPACE, NAMESPACE-SELECTOR and LOCATION-RESOURCE columns.")
+	cmd.Flags().BoolVar(&o.IncludeMatchedLocations, "include-matched-locations", o.IncludeMatchedLocations, "With -o yaml, also print the Locations selected by the placement, "+
+		"for a complete snapshot of the binding decision.")
+	cmd.Flags().BoolVar(&o.PrintRef, "print-ref", o.PrintRef, "Print a reference to each placement as <workspace path>/placements/<name> once ready, "+
+		"to chain the bind into other commands. Progress messages are printed to stderr.")
+	cmd.Flags().StringVar(&o.OutputFile, "output-file", o.OutputFile, "File to write the objects printed with --output to, instead of stdout. Progress messages are still printed to stdout.")
+	cmd.Flags().BoolVar(&o.AnnotateWithSource, "annotate-with-source", o.AnnotateWithSource, "Annotate the created APIBindings and placement with the version of the plugin, "+
+		"and the user running it when it can be told from the kubeconfig, for audit trails.")
+	cmd.Flags().StringSliceVar(&o.OwnerRefs, "owner-ref", o.OwnerRefs, "Owner reference to set on the created APIBindings and placement, as apiVersion/Kind/name/uid, "+
+		"so that they are garbage collected with the owner. The owner must live in the same workspace.")
+	cmd.Flags().StringVar(&o.FailureReport, "failure-report", o.FailureReport, "File to write a JSON report to when the bind fails, with the requested and supported APIExports, "+
+		"and the readiness of the created objects at the time of the failure, e.g. to attach it to CI build logs.")
+	cmd.Flags().BoolVar(&o.FailFast, "fail-fast", o.FailFast, "Stop waiting for readiness as soon as an APIBinding or the placement reports a failure that does not resolve by waiting, "+
+		"like a missing APIExport, a naming conflict, invalid permission claims, or no location matching the location selectors.")
+	cmd.Flags().BoolVar(&o.ContinueOnBindingError, "continue-on-binding-error", o.ContinueOnBindingError, "When some APIBindings fail to be created, still create the placement and wait for the others, "+
+		fmt.Sprintf("then exit with code %d rather than 1 to tell the partial success from a failure.", PartialBindExitCode))
+	cmd.Flags().StringVar(&o.DebugDumpOnFailure, "debug-dump-on-failure", o.DebugDumpOnFailure, "File to write the YAML of the placement, the APIBindings, and the SyncTargets and Locations "+
+		"of the location workspace to when the bind fails, status included and sensitive annotations redacted, to diagnose the failure without reproducing it.")
+	cmd.Flags().StringVar(&o.CallbackURL, "callback-url", o.CallbackURL, "URL to POST the result of the bind to as JSON when it is done, with the placement names, the status, and the readiness of the APIBindings, "+
+		"e.g. to notify an external system. Failing to post is only a warning.")
+	cmd.Flags().StringVar(&o.ObjectsDir, "objects-dir", o.ObjectsDir, "Directory to write each of the APIBindings and the placement to, as <kind>-<name>.yaml without server-populated fields, e.g. to commit them to a GitOps repository.")
+	o.bindClientFlags(cmd)
+}
+
+// BindValidateFlags binds the fields used by the validate subcommand as command line flags to cmd's flagset.
+func (o *BindComputeOptions) BindValidateFlags(cmd *cobra.Command) {
+	o.Options.BindFlags(cmd)
+	o.bindSelectionFlags(cmd)
+	o.bindClientFlags(cmd)
+	o.flags = cmd.Flags()
+}
+
+// CompleteValidate completes the options of bind compute validate, which checks a single location workspace.
+func (o *BindComputeOptions) CompleteValidate(args []string) error {
+	if len(args) == 1 && args[0] == wildcardLocationWorkspace {
+		return fmt.Errorf("a wildcard location workspace cannot be validated, specify a single location workspace")
+	}
+	return o.Complete(args)
+}
+
+// bindClientFlags binds the flags configuring the kcp clients to cmd's flagset.
+func (o *BindComputeOptions) bindClientFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.UserAgent, "user-agent", o.UserAgent, "User agent to set on requests to kcp. Defaults to kcp-bind-compute/<version>.")
+	cmd.Flags().Float32Var(&o.QPS, "qps", o.QPS, "QPS to use when talking to kcp. Raise it together with --burst when binding many APIExports.")
+	cmd.Flags().IntVar(&o.Burst, "burst", o.Burst, "Burst to use when talking to kcp.")
+	cmd.Flags().StringVar(&o.DirectURL, "direct-url", o.DirectURL, "URL to reach the location workspace and other workspaces under, as <url>/clusters/<workspace>, "+
+		"instead of the kcp server the current workspace is served by. Use it with a virtual workspace URL, or when the current server URL is not a workspace URL.")
+}
+
+// bindSelectionFlags binds the flags selecting the APIExports, namespaces and locations to cmd's flagset.
+func (o *BindComputeOptions) bindSelectionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&o.APIExports, "apiexports", o.APIExports,
+		"APIExport to bind to this workspace for workload, each APIExport should be in the format of <absolute_ref_to_workspace>:<apiexport>. Defaults to $KCP_BIND_APIEXPORTS if set.")
+	cmd.Flags().StringSliceVar(&o.ExcludeAPIExports, "exclude-apiexports", o.ExcludeAPIExports, "APIExport not to bind, as <absolute_ref_to_workspace>:<apiexport>, "+
+		"removed from the APIExports selected by default, by --refresh or by --apiexports-from-workspace. Fails if it is not among them.")
+	cmd.Flags().BoolVar(&o.IgnoreMissingExcludes, "ignore-missing-excludes", o.IgnoreMissingExcludes, "Ignore --exclude-apiexports not among the APIExports to bind, instead of failing.")
+	cmd.Flags().StringVar(&o.KubernetesVersion, "bind-kubernetes-version", o.KubernetesVersion,
+		"Without --apiexports, bind the kubernetes-<version> APIExport instead of the kubernetes one, for deployments exporting versioned variants, e.g. v1-24.")
+	cmd.Flags().StringSliceVar(&o.ExpectIdentities, "expect-identity", o.ExpectIdentities, "Identity hash the APIExport must have to be bound, as <absolute_ref_to_workspace>:<apiexport>=<hash>, "+
+		"or just <hash> when binding a single APIExport. Binding is refused on mismatch, to protect against APIExports shadowing well-known names.")
+	cmd.Flags().BoolVar(&o.VerifyExportEndpoints, "verify-export-endpoints", o.VerifyExportEndpoints, "Warn about APIExports whose virtual workspace endpoints are not ready, as they cannot serve yet.")
+	cmd.Flags().BoolVar(&o.IgnoreUnsupported, "ignore-unsupported", o.IgnoreUnsupported, "Skip APIExports not supported by the synctargets in the location workspace with a warning, instead of failing.")
+	cmd.Flags().StringVar(&o.NamespaceSelectorString, "namespace-selector", o.NamespaceSelectorString, "Label select to select namespaces to create workload.")
+	cmd.Flags().StringArrayVar(&o.NamespaceMatchExpressions, "namespace-match-expression", o.NamespaceMatchExpressions,
+		"Requirement added to the namespace selector, as key,operator[,value...], with operator one of In, NotIn, Exists, DoesNotExist, e.g. env,In,prod,staging. Can be repeated.")
+	cmd.Flags().StringVar(&o.NamespacePreset, "namespace-preset", o.NamespacePreset,
+		fmt.Sprintf("Name of a predefined namespace selector to use, one of %s. --namespace-selector takes precedence over it.", strings.Join(namespacePresetNames(), ", ")))
+	cmd.Flags().StringSliceVar(&o.LocationSelectorsStrings, "location-selectors", o.LocationSelectorsStrings,
+		"A list of label selectors to select locations in the location workspace to sync workload, separated by commas or newlines, e.g. from a file. Defaults to $KCP_BIND_LOCATION_SELECTORS if set.")
+	cmd.Flags().StringArrayVar(&o.LocationMatchLabels, "location-match-labels", o.LocationMatchLabels,
+		"Labels the locations must have, as key=value pairs separated by commas, e.g. region=us,tier=gold. Added to --location-selectors. Can be repeated for alternative label sets.")
+	cmd.Flags().StringVar(&o.SyncTargetSelector, "synctarget-selector", o.SyncTargetSelector,
+		"Label selector restricting the synctargets whose supported APIExports are bound. Unlike --location-selectors, which select "+
+			"the locations the placement schedules to, this only filters the APIExports: the placement can still schedule to any synctarget of the selected locations.")
+	cmd.Flags().StringVar(&o.FromSyncTarget, "from-synctarget", o.FromSyncTarget, "Name of the synctarget in the location workspace whose supported APIExports are bound, "+
+		"instead of those of any synctarget, to pin the bind to the capabilities of that synctarget.")
+	cmd.Flags().StringVar(&o.LocationResource, "location-resource", o.LocationResource, "Resource the placement selects locations of, as <resource>.<version>.<group>, "+
+		"with the plural lowercase resource name.")
+	cmd.Flags().BoolVar(&o.AllNamespaces, "all-namespaces", o.AllNamespaces, "Select all namespaces to create workload. Mutually exclusive with --namespace-selector.")
+	cmd.Flags().BoolVar(&o.AllLocations, "all-locations", o.AllLocations, "Select all locations in the location workspace. Mutually exclusive with --location-selectors.")
+}
+
+// wildcardLocationWorkspace is the location workspace argument binding to every accessible workspace with SyncTargets
+// supporting the APIExports, with a placement in each.
+const wildcardLocationWorkspace = "*"
+
+// Complete ensures all dynamically populated fields are initialized.
+func (o *BindComputeOptions) Complete(args []string) error {
+	// with --server and --token, the kubeconfig files are not loaded at all, e.g. in scripts authenticating with a
+	// service account token.
+	if o.tokenOnly() {
+		o.ClientConfig = clientcmd.NewDefaultClientConfig(*clientcmdapi.NewConfig(), o.KubectlOverrides)
+	} else if err := o.Options.Complete(); err != nil {
+		return err
+	}
+
+	if len(o.Batch) > 0 {
+		if len(args) > 0 || o.DiscoverLocation {
+			return fmt.Errorf("a location workspace cannot be specified with --batch, each bind request has its own")
+		}
+	} else if len(args) == 1 && o.DiscoverLocation {
+		return fmt.Errorf("a location workspace cannot be specified with --discover-location")
+	} else if len(args) != 1 && !o.DiscoverLocation {
+		return fmt.Errorf("a location workspace should be specified")
+	}
+
+	if err := o.applyEnvironment(); err != nil {
+		return err
+	}
+
+	if len(o.Profile) > 0 {
+		if err := o.applyProfile(); err != nil {
+			return err
+		}
+	}
+
+	if len(o.Batch) > 0 {
+		return o.completeBatch()
+	}
+	if len(args) == 1 && args[0] == wildcardLocationWorkspace {
+		// the location workspaces are discovered in Run, once the options are validated.
+		o.wildcard = true
+		return nil
+	}
+	return o.completeBind(args)
+}
+
+// completeBind initializes the fields of a single bind to the location workspace given as argument, if any.
+func (o *BindComputeOptions) completeBind(args []string) error {
+	if len(args) == 1 {
+		locationWorkspace := args[0]
+		if !isAbsoluteWorkspacePath(locationWorkspace) {
+			// relative to the current workspace
+			currentClusterName, err := o.currentWorkspace()
+			if err != nil {
+				return err
+			}
+			locationWorkspace = currentClusterName.Join(locationWorkspace).String()
+		}
+		clusterName, validated := logicalcluster.NewValidated(locationWorkspace)
+		if !validated {
+			return fmt.Errorf("location workspace type is incorrect")
+		}
+		o.LocationWorkspace = clusterName
+	}
+
+	if len(o.TargetWorkspace) > 0 {
+		targetWorkspace, validated := logicalcluster.NewValidated(o.TargetWorkspace)
+		if !validated || !strings.HasPrefix(o.TargetWorkspace, "root") {
+			return fmt.Errorf("target workspace %q must be an absolute workspace path like root:org:team", o.TargetWorkspace)
+		}
+		o.targetWorkspace = targetWorkspace
+	}
+
+	if len(o.APIExportsFromWorkspace) > 0 {
+		apiExportsFromWorkspace, validated := logicalcluster.NewValidated(o.APIExportsFromWorkspace)
+		if !validated || !isAbsoluteWorkspacePath(o.APIExportsFromWorkspace) {
+			return fmt.Errorf("apiexports workspace %q must be an absolute workspace path like root:shared", o.APIExportsFromWorkspace)
+		}
+		o.apiExportsFromWorkspace = apiExportsFromWorkspace
+	}
+
+	// report all selector errors at once, so they can be fixed in one go.
+	errs := o.completeSelectors()
+
+	if gvr, _ := schema.ParseResourceArg(o.LocationResource); gvr == nil {
+		errs = append(errs, fmt.Errorf("location resource %q must be in the format <resource>.<version>.<group>, e.g. %s", o.LocationResource, formatLocationResource(defaultLocationResource)))
+	} else {
+		o.locationResource = schedulingv1alpha1.GroupVersionResource{Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource}
+	}
+
+	if _, err := labels.Parse(o.SyncTargetSelector); err != nil {
+		errs = append(errs, fmt.Errorf("synctarget selector %s format not correct: %w", o.SyncTargetSelector, err))
+	}
+
+	if len(o.ExpectIdentities) > 0 {
+		o.expectedIdentities = map[string]string{}
+	}
+	for _, expected := range o.ExpectIdentities {
+		export, hash := "", expected
+		if i := strings.LastIndex(expected, "="); i >= 0 {
+			export, hash = expected[:i], expected[i+1:]
+		}
+		if len(hash) == 0 {
+			errs = append(errs, fmt.Errorf("expected identity %q must not have an empty hash", expected))
+			continue
+		}
+		if _, ok := o.expectedIdentities[export]; ok {
+			errs = append(errs, fmt.Errorf("expected identity %q is given twice for the same APIExport", expected))
+			continue
+		}
+		o.expectedIdentities[export] = hash
+	}
+	for _, ownerRef := range o.OwnerRefs {
+		ownerReference, err := parseOwnerReference(ownerRef)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		o.ownerReferences = append(o.ownerReferences, ownerReference)
+	}
+	if len(errs) > 0 {
+		return utilerrors.NewAggregate(errs)
+	}
+
+	// with --discover-location, the placements are named again once the location workspace is discovered in Run.
+	o.defaultPlacementNames()
+	return nil
+}
+
+// completeSelectors parses the namespace and location selectors, and the placements given with --placement. It can
+// be called again once the selectors are copied with --selectors-from.
+func (o *BindComputeOptions) completeSelectors() []error {
+	o.namespaceSelector = nil
+	o.locationSelectors = nil
+	o.placements = nil
+
+	var errs []error
+	namespaceSelectorString := o.NamespaceSelectorString
+	if o.AllNamespaces {
+		namespaceSelectorString = labels.Everything().String()
+	} else if len(o.NamespacePreset) > 0 {
+		preset, ok := namespaceSelectorPresets[o.NamespacePreset]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown namespace preset %q, must be one of %s", o.NamespacePreset, strings.Join(namespacePresetNames(), ", ")))
+		} else if namespaceSelectorString == labels.Everything().String() {
+			namespaceSelectorString = preset
+		}
+	}
+	var err error
+	if o.namespaceSelector, err = metav1.ParseToLabelSelector(namespaceSelectorString); err != nil {
+		errs = append(errs, fmt.Errorf("namespace selector format not correct: %w", err))
+	} else if err := validateSelectorOperators(o.namespaceSelector); err != nil {
+		errs = append(errs, fmt.Errorf("namespace selector %s is not supported: %w", namespaceSelectorString, err))
+	} else {
+		for _, expression := range o.NamespaceMatchExpressions {
+			requirement, err := parseMatchExpression(expression)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			o.namespaceSelector.MatchExpressions = append(o.namespaceSelector.MatchExpressions, requirement)
+		}
+		if len(o.NamespaceMatchExpressions) > 0 {
+			// the expressions select different namespaces, so they make a different placement.
+			namespaceSelectorString = metav1.FormatLabelSelector(o.namespaceSelector)
+		}
+	}
+
+	o.LocationSelectorsStrings = normalizeSelectorList(o.LocationSelectorsStrings)
+	if len(o.LocationMatchLabels) > 0 {
+		// the default selector matching everything would make the match labels pointless.
+		if len(o.LocationSelectorsStrings) == 1 && o.LocationSelectorsStrings[0] == labels.Everything().String() {
+			o.LocationSelectorsStrings = nil
+		}
+		for _, matchLabels := range o.LocationMatchLabels {
+			set, err := labels.ConvertSelectorToLabelsMap(matchLabels)
+			if err != nil || len(set) == 0 {
+				errs = append(errs, fmt.Errorf("location match labels %q must be key=value pairs separated by commas: %v", matchLabels, err))
+				continue
+			}
+			// the selectors are completed again with --selectors-from, the match labels are only appended once.
+			if !sets.NewString(o.LocationSelectorsStrings...).Has(set.String()) {
+				o.LocationSelectorsStrings = append(o.LocationSelectorsStrings, set.String())
+			}
+		}
+	}
+	locationSelectorsStrings := o.LocationSelectorsStrings
+	if o.AllLocations {
+		locationSelectorsStrings = []string{labels.Everything().String()}
+	}
+	for _, locSelector := range locationSelectorsStrings {
+		selector, err := metav1.ParseToLabelSelector(locSelector)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("location selector %s format not correct: %w", locSelector, err))
+			continue
+		}
+		if err := validateSelectorOperators(selector); err != nil {
+			errs = append(errs, fmt.Errorf("location selector %s is not supported: %w", locSelector, err))
+			continue
+		}
+		o.locationSelectors = append(o.locationSelectors, *selector)
+	}
+	for _, tuple := range o.Placements {
+		placement, err := parsePlacementTuple(tuple, namespaceSelectorString)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		o.placements = append(o.placements, placement)
+	}
+	o.namespaceSelectorString = namespaceSelectorString
+	return errs
+}
+
+// defaultPlacementNames names the placements not named explicitly after their selectors.
+func (o *BindComputeOptions) defaultPlacementNames() {
+	if len(o.PlacementName) == 0 || o.placementNameDefaulted {
+		o.PlacementName = placementName(o.namespaceSelectorString, o.LocationSelectorsStrings, o.LocationWorkspace)
+		o.placementNameDefaulted = true
+	}
+	for i := range o.placements {
+		if len(o.placements[i].name) == 0 {
+			o.placements[i].name = placementName(o.placements[i].namespaceSelectorString, o.placements[i].locationSelectorsStrings, o.LocationWorkspace)
+		}
+	}
+}
+
+// placementName returns the default name of the placement, a hash of location selectors and ns selector, with
+// location workspace name as the prefix.
+func placementName(namespaceSelectorString string, locationSelectorsStrings []string, locationWorkspace logicalcluster.Name) string {
+	hash := sha256.Sum224([]byte(namespaceSelectorString + strings.Join(locationSelectorsStrings, ",") + locationWorkspace.String()))
+	base36hash := strings.ToLower(base36.EncodeBytes(hash[:]))
+	return fmt.Sprintf("placement-%s", base36hash[:8])
+}
+
+// placementOptions are the name and selectors of one of the placements given with --placement.
+type placementOptions struct {
+	name                     string
+	namespaceSelectorString  string
+	namespaceSelector        *metav1.LabelSelector
+	locationSelectorsStrings []string
+	locationSelectors        []metav1.LabelSelector
+}
+
+// parsePlacementTuple parses a name=<name>,location-selectors=<selector>,namespace-selector=<selector> tuple given
+// with --placement. Selectors might contain commas themselves, so a part not starting with one of the keys continues
+// the previous value. The namespace selector defaults to the given one, and the location selectors to everything.
+func parsePlacementTuple(tuple string, defaultNamespaceSelector string) (placementOptions, error) {
+	placement := placementOptions{namespaceSelectorString: defaultNamespaceSelector}
+
+	var values []*string
+	for _, part := range strings.Split(tuple, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch strings.TrimSpace(key) {
+		case "name":
+			placement.name = value
+			values = append(values, &placement.name)
+		case "namespace-selector":
+			placement.namespaceSelectorString = value
+			values = append(values, &placement.namespaceSelectorString)
+		case "location-selectors":
+			placement.locationSelectorsStrings = append(placement.locationSelectorsStrings, value)
+			// the value is appended to, so that it is continued in place.
+			values = append(values, nil)
+		default:
+			if len(values) == 0 {
+				return placementOptions{}, fmt.Errorf("placement %q must start with one of name=, location-selectors= or namespace-selector=", tuple)
+			}
+			if last := values[len(values)-1]; last != nil {
+				*last += "," + part
+			} else {
+				placement.locationSelectorsStrings[len(placement.locationSelectorsStrings)-1] += "," + part
+			}
+		}
+	}
+	if len(placement.locationSelectorsStrings) == 0 {
+		placement.locationSelectorsStrings = []string{labels.Everything().String()}
+	}
+
+	var errs []error
+	var err error
+	if placement.namespaceSelector, err = metav1.ParseToLabelSelector(placement.namespaceSelectorString); err != nil {
+		errs = append(errs, fmt.Errorf("namespace selector format not correct: %w", err))
+	} else if err := validateSelectorOperators(placement.namespaceSelector); err != nil {
+		errs = append(errs, fmt.Errorf("namespace selector %s is not supported: %w", placement.namespaceSelectorString, err))
+	}
+	for _, locSelector := range placement.locationSelectorsStrings {
+		selector, err := metav1.ParseToLabelSelector(locSelector)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("location selector %s format not correct: %w", locSelector, err))
+			continue
+		}
+		if err := validateSelectorOperators(selector); err != nil {
+			errs = append(errs, fmt.Errorf("location selector %s is not supported: %w", locSelector, err))
+			continue
+		}
+		placement.locationSelectors = append(placement.locationSelectors, *selector)
+	}
+	if len(errs) > 0 {
+		return placementOptions{}, fmt.Errorf("invalid placement %q: %w", tuple, utilerrors.NewAggregate(errs))
+	}
+	return placement, nil
+}
+
+// placementOptions returns the options to create each placement with: one per --placement, sharing everything but
+// the placement name and selectors, or just the options themselves without --placement.
+func (o *BindComputeOptions) placementOptions() []*BindComputeOptions {
+	if len(o.placements) == 0 {
+		return []*BindComputeOptions{o}
+	}
+
+	var placementOpts []*BindComputeOptions
+	for _, placement := range o.placements {
+		po := *o
+		po.PlacementName = placement.name
+		po.namespaceSelector = placement.namespaceSelector
+		po.locationSelectors = placement.locationSelectors
+		po.LocationSelectorsStrings = placement.locationSelectorsStrings
+		placementOpts = append(placementOpts, &po)
+	}
+	return placementOpts
+}
+
+// parseMatchExpression parses a key,operator[,value...] requirement given with --namespace-match-expression. The
+// operator is matched case-insensitively, and values are only allowed with In and NotIn.
+func parseMatchExpression(expression string) (metav1.LabelSelectorRequirement, error) {
+	parts := strings.Split(expression, ",")
+	if len(parts) < 2 {
+		return metav1.LabelSelectorRequirement{}, fmt.Errorf("match expression %q must be in the format key,operator[,value...]", expression)
+	}
+
+	requirement := metav1.LabelSelectorRequirement{Key: strings.TrimSpace(parts[0])}
+	for _, operator := range []metav1.LabelSelectorOperator{metav1.LabelSelectorOpIn, metav1.LabelSelectorOpNotIn, metav1.LabelSelectorOpExists, metav1.LabelSelectorOpDoesNotExist} {
+		if strings.EqualFold(strings.TrimSpace(parts[1]), string(operator)) {
+			requirement.Operator = operator
+		}
+	}
+	if len(requirement.Operator) == 0 {
+		requirement.Operator = metav1.LabelSelectorOperator(strings.TrimSpace(parts[1]))
+	}
+	for _, value := range parts[2:] {
+		requirement.Values = append(requirement.Values, strings.TrimSpace(value))
+	}
+
+	var errs []error
+	if msgs := validation.IsQualifiedName(requirement.Key); len(msgs) > 0 {
+		errs = append(errs, fmt.Errorf("invalid key %q: %s", requirement.Key, strings.Join(msgs, ", ")))
+	}
+	for _, value := range requirement.Values {
+		if msgs := validation.IsValidLabelValue(value); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("invalid value %q: %s", value, strings.Join(msgs, ", ")))
+		}
+	}
+	if err := validateSelectorOperators(&metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{requirement}}); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return metav1.LabelSelectorRequirement{}, fmt.Errorf("invalid match expression %q: %w", expression, utilerrors.NewAggregate(errs))
+	}
+	return requirement, nil
+}
+
+// normalizeSelectorList splits the given selectors on newlines as well, e.g. when piped from a file, and drops blank
+// ones. Without any selector left, the selector matching everything is returned.
+func normalizeSelectorList(selectors []string) []string {
+	var normalized []string
+	for _, selector := range selectors {
+		for _, line := range strings.Split(selector, "\n") {
+			if line = strings.TrimSpace(line); len(line) > 0 {
+				normalized = append(normalized, line)
+			}
+		}
+	}
+	if len(normalized) == 0 {
+		return []string{labels.Everything().String()}
+	}
+	return normalized
+}
+
+// namespaceSelectorPresets are the namespace selectors available by name with --namespace-preset.
+var namespaceSelectorPresets = map[string]string{
+	// only namespaces opted into workloads.
+	"labeled": "kcp.dev/workload=true",
+	// all namespaces but those opted out of workloads.
+	"opt-out": "kcp.dev/workload!=false",
+}
+
+// unboundedTimeouts are the keywords accepted by --timeout, in addition to 0, to wait forever.
+var unboundedTimeouts = sets.NewString("never", "infinite")
+
+// timeoutValue is the value of a duration flag that also accepts the unbounded timeout keywords, parsed as 0.
+type timeoutValue time.Duration
+
+func newTimeoutValue(value time.Duration, p *time.Duration) *timeoutValue {
+	*p = value
+	return (*timeoutValue)(p)
+}
+
+func (d *timeoutValue) Set(s string) error {
+	if unboundedTimeouts.Has(strings.ToLower(s)) {
+		*d = 0
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = timeoutValue(v)
+	return nil
+}
+
+func (d *timeoutValue) Type() string {
+	return "duration"
+}
+
+func (d *timeoutValue) String() string {
+	return (*time.Duration)(d).String()
+}
+
+// namespacePresetNames returns the sorted names of the namespace selector presets.
+func namespacePresetNames() []string {
+	return sets.StringKeySet(namespaceSelectorPresets).List()
+}
+
+// kindRegexp matches the CamelCase kinds of Kubernetes resources.
+var kindRegexp = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+
+// parseOwnerReference parses an owner reference given as apiVersion/Kind/name/uid. The apiVersion might contain a
+// slash itself, e.g. apps/v1/Deployment/name/uid.
+func parseOwnerReference(ownerRef string) (metav1.OwnerReference, error) {
+	parts := strings.Split(ownerRef, "/")
+	if len(parts) < 4 {
+		return metav1.OwnerReference{}, fmt.Errorf("owner reference %q must be in the format apiVersion/Kind/name/uid", ownerRef)
+	}
+	n := len(parts)
+	apiVersion, kind, name, uid := strings.Join(parts[:n-3], "/"), parts[n-3], parts[n-2], parts[n-1]
+
+	var errs []error
+	if _, err := schema.ParseGroupVersion(apiVersion); err != nil || len(apiVersion) == 0 {
+		errs = append(errs, fmt.Errorf("invalid apiVersion %q", apiVersion))
+	}
+	if !kindRegexp.MatchString(kind) {
+		errs = append(errs, fmt.Errorf("invalid kind %q, must be CamelCase like Deployment", kind))
+	}
+	if len(name) == 0 {
+		errs = append(errs, errors.New("name must not be empty"))
+	}
+	if _, err := uuid.Parse(uid); err != nil {
+		errs = append(errs, fmt.Errorf("invalid uid %q: %w", uid, err))
+	}
+	if len(errs) > 0 {
+		return metav1.OwnerReference{}, fmt.Errorf("invalid owner reference %q: %w", ownerRef, utilerrors.NewAggregate(errs))
+	}
+
+	return metav1.OwnerReference{
+		APIVersion: apiVersion,
+		Kind:       kind,
+		Name:       name,
+		UID:        types.UID(uid),
+	}, nil
+}
+
+// printSummary prints a one-line summary of the bind, to o.Out if it is ready and to o.ErrOut otherwise. With
+// --verbose, the state of each APIBinding and the placement is printed as well. APIBindings waiting for permission
+// claims to be accepted are reported with --verbose or when the bind is not ready.
+func (o *BindComputeOptions) printSummary(bindings []*apisv1alpha1.APIBinding, placement *schedulingv1alpha1.Placement, elapsed time.Duration) error {
+	if o.Verbose {
+		if err := o.printStatus("", bindings, placement); err != nil {
+			return err
+		}
+	}
+
+	ready := o.bindReady(bindings, placement)
+	// the table is only printed along with the progress messages, not with an output meant to be parsed.
+	if o.Output == "wide" || (o.Table && o.Output == "") {
+		out := o.Out
+		if !ready {
+			out = o.ErrOut
+		}
+		if err := o.printTable(out, bindings, placement); err != nil {
+			return err
+		}
+	}
+
+	if ready {
+		_, err := fmt.Fprintf(o.Out, "bound %d APIExport(s) with placement %s, ready in %s.\n", len(bindings), placement.Name, elapsed.Round(time.Millisecond*100))
+		return err
+	}
+
+	boundCount := 0
+	for _, binding := range bindings {
+		if binding.Status.Phase == apisv1alpha1.APIBindingPhaseBound {
+			boundCount++
+			continue
+		}
+		if !o.Verbose {
+			if err := printPendingPermissionClaims(o.ErrOut, binding); err != nil {
+				return err
+			}
+		}
+	}
+	placementState := "placement ready"
+	if !o.placementReady(placement) {
+		placementState = "placement not ready"
+	}
+	_, err := fmt.Fprintf(o.ErrOut, "bind failed: %d of %d bindings ready, %s.\n", boundCount, len(bindings), placementState)
+	return err
+}
+
+// printStatus prints the state of each APIBinding and of the placement, each line starting with the given prefix.
+func (o *BindComputeOptions) printStatus(prefix string, bindings []*apisv1alpha1.APIBinding, placement *schedulingv1alpha1.Placement) error {
+	for _, binding := range bindings {
+		if _, err := fmt.Fprintf(o.Out, "%sapibinding %s for apiexport %s: phase %q\n", prefix, binding.Name, exportReferenceKey(binding.Spec.Reference.Workspace), binding.Status.Phase); err != nil {
+			return err
+		}
+		if pending := pendingPermissionClaims(binding); len(pending) > 0 {
+			if _, err := fmt.Fprint(o.Out, prefix); err != nil {
+				return err
+			}
+			if err := printPendingPermissionClaims(o.Out, binding); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintf(o.Out, "%splacement %s: phase %q, ready %t\n", prefix, placement.Name, placement.Status.Phase, o.placementReady(placement))
+	return err
+}
 
-	// APIExports is a list of APIExport to use in the workspace.
-	APIExports []string
+// printPendingPermissionClaims tells the user which permission claims the binding is waiting for, if any.
+func printPendingPermissionClaims(out io.Writer, binding *apisv1alpha1.APIBinding) error {
+	pending := pendingPermissionClaims(binding)
+	if len(pending) == 0 {
+		return nil
+	}
+	_, err := fmt.Fprintf(out, "apibinding %s is waiting for acceptance of claims: [%s]. Accept them with --accept-permission-claims, or by editing the apibinding.\n", binding.Name, strings.Join(pending, ","))
+	return err
+}
 
-	// Namespace selector is a label selector to select namespace for the workload.
-	namespaceSelector       *metav1.LabelSelector
-	NamespaceSelectorString string
+// pendingPermissionClaims returns the permission claims requested by the APIExport that are neither accepted nor
+// rejected in the binding's spec.
+func pendingPermissionClaims(binding *apisv1alpha1.APIBinding) []string {
+	var pending []string
+	for _, exportedClaim := range binding.Status.ExportPermissionClaims {
+		resolved := false
+		for _, specClaim := range binding.Spec.PermissionClaims {
+			if exportedClaim.Equal(specClaim.PermissionClaim) && (specClaim.State == apisv1alpha1.ClaimAccepted || specClaim.State == apisv1alpha1.ClaimRejected) {
+				resolved = true
+				break
+			}
+		}
+		if !resolved {
+			pending = append(pending, permissionClaimKey(exportedClaim))
+		}
+	}
+	return pending
+}
 
-	// LocationSelectors is a list of label selectors to select locations in the location workspace.
-	locationSelectors        []metav1.LabelSelector
-	LocationSelectorsStrings []string
+var (
+	// ErrWaitTimeout is returned when the bind is not ready before --timeout expires. It wraps wait.ErrWaitTimeout.
+	ErrWaitTimeout = fmt.Errorf("--timeout expired: %w", wait.ErrWaitTimeout)
 
-	// LocationWorkspace is the workspace for synctarget
-	LocationWorkspace logicalcluster.Name
+	// ErrWaitInterrupted is returned when waiting for readiness is interrupted by the caller, e.g. on Ctrl-C or when
+	// --deadline expires. The returned error also wraps the context error.
+	ErrWaitInterrupted = errors.New("interrupted while waiting for readiness")
+)
 
-	// BindWaitTimeout is how long to wait for the placement to be created and successful.
-	BindWaitTimeout time.Duration
+// waitInterruptedError is ErrWaitInterrupted, wrapping the error of the context that interrupted the wait.
+type waitInterruptedError struct {
+	cause error
 }
 
-func NewBindComputeOptions(streams genericclioptions.IOStreams) *BindComputeOptions {
-	return &BindComputeOptions{
-		Options:                 base.NewOptions(streams),
-		NamespaceSelectorString: labels.Everything().String(),
-		LocationSelectorsStrings: []string{
-			labels.Everything().String(),
-		},
-	}
+func (e *waitInterruptedError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrWaitInterrupted, e.cause)
 }
 
-// BindFlags binds fields SyncOptions as command line flags to cmd's flagset.
-func (o *BindComputeOptions) BindFlags(cmd *cobra.Command) {
-	o.Options.BindFlags(cmd)
+func (e *waitInterruptedError) Is(target error) bool {
+	return target == ErrWaitInterrupted
+}
 
-	cmd.Flags().StringSliceVar(&o.APIExports, "apiexports", o.APIExports,
-		"APIExport to bind to this workspace for workload, each APIExport should be in the format of <absolute_ref_to_workspace>:<apiexport>")
-	cmd.Flags().StringVar(&o.NamespaceSelectorString, "namespace-selector", o.NamespaceSelectorString, "Label select to select namespaces to create workload.")
-	cmd.Flags().StringSliceVar(&o.LocationSelectorsStrings, "location-selectors", o.LocationSelectorsStrings,
-		"A list of label selectors to select locations in the location workspace to sync workload.")
-	cmd.Flags().StringVar(&o.PlacementName, "name", o.PlacementName, "Name of the placement to be created.")
-	cmd.Flags().DurationVar(&o.BindWaitTimeout, "timeout", time.Second*30, "Duration to wait for Placement to be created and bound successfully.")
+func (e *waitInterruptedError) Unwrap() error {
+	return e.cause
 }
 
-// Complete ensures all dynamically populated fields are initialized.
-func (o *BindComputeOptions) Complete(args []string) error {
-	if err := o.Options.Complete(); err != nil {
-		return err
+// pollUntilReady checks the condition at an exponentially growing, jittered interval, capped at --poll-max-interval,
+// until it is done, it fails, or --timeout expires. A --timeout of zero waits forever. ErrWaitTimeout is returned
+// when --timeout expires, and ErrWaitInterrupted when ctx is done first.
+func (o *BindComputeOptions) pollUntilReady(parentCtx context.Context, condition wait.ConditionWithContextFunc) error {
+	ctx := parentCtx
+	if o.BindWaitTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.BindWaitTimeout)
+		defer cancel()
+	}
+	waitErr := func() error {
+		if err := parentCtx.Err(); err != nil {
+			return &waitInterruptedError{cause: err}
+		}
+		return fmt.Errorf("%w after %s", ErrWaitTimeout, o.BindWaitTimeout)
 	}
 
-	if len(args) != 1 {
-		return fmt.Errorf("a location workspace should be specified")
+	backoff := wait.Backoff{
+		Duration: o.PollInterval,
+		Factor:   o.PollFactor,
+		Jitter:   o.PollJitter,
+		// Step only grows the interval while steps are left, until the cap is reached.
+		Steps: math.MaxInt32,
+		Cap:   o.PollMaxInterval,
 	}
-	clusterName, validated := logicalcluster.NewValidated(args[0])
-	if !validated {
-		return fmt.Errorf("location workspace type is incorrect")
+	for {
+		done, err := condition(ctx)
+		if ctx.Err() != nil {
+			return waitErr()
+		}
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return waitErr()
+		case <-time.After(backoff.Step()):
+		}
 	}
-	o.LocationWorkspace = clusterName
+}
 
-	var err error
-	if o.namespaceSelector, err = metav1.ParseToLabelSelector(o.NamespaceSelectorString); err != nil {
-		return fmt.Errorf("namespace selector format not correct: %w", err)
+// RunValidate checks the options against the location workspace without creating anything. All issues found are
+// reported, and an error is returned if there are any.
+func (o *BindComputeOptions) RunValidate(ctx context.Context) error {
+	_, kcpClient, err := o.newClients()
+	if err != nil {
+		return err
 	}
 
-	for _, locSelector := range o.LocationSelectorsStrings {
-		selector, err := metav1.ParseToLabelSelector(locSelector)
-		if err != nil {
-			return fmt.Errorf("location selector %s format not correct: %w", locSelector, err)
+	issues := o.validateIssues(ctx, kcpClient)
+	if len(issues) == 0 {
+		_, err := fmt.Fprintf(o.Out, "no issues found binding compute to location workspace %s.\n", o.LocationWorkspace)
+		return err
+	}
+
+	for _, issue := range issues {
+		if _, err := fmt.Fprintf(o.Out, "- %s\n", issue); err != nil {
+			return err
 		}
-		o.locationSelectors = append(o.locationSelectors, *selector)
 	}
+	return fmt.Errorf("found %d issue(s) binding compute to location workspace %s", len(issues), o.LocationWorkspace)
+}
 
-	if len(o.PlacementName) == 0 {
-		// placement name is a hash of location selectors and ns selector, with location workspace name as the prefix
-		hash := sha256.Sum224([]byte(o.NamespaceSelectorString + strings.Join(o.LocationSelectorsStrings, ",") + o.LocationWorkspace.String()))
-		base36hash := strings.ToLower(base36.EncodeBytes(hash[:]))
-		o.PlacementName = fmt.Sprintf("placement-%s", base36hash[:8])
+// validateIssues returns the issues binding compute to the location workspace. The APIExports and the selectors are
+// only checked once the location workspace could be read, the issues would be unrelated otherwise.
+func (o *BindComputeOptions) validateIssues(ctx context.Context, kcpClient kcpclient.ClusterInterface) []string {
+	parentClusterName, workspaceName := o.LocationWorkspace.Split()
+	_, err := kcpClient.Cluster(parentClusterName).TenancyV1beta1().Workspaces().Get(ctx, workspaceName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return []string{fmt.Sprintf("location workspace %s not found", o.LocationWorkspace)}
+	}
+	if err != nil {
+		return []string{fmt.Sprintf("failed to get location workspace %s: %v", o.LocationWorkspace, err)}
 	}
 
-	return nil
-}
+	var issues []string
+	locationClient := kcpClient.Cluster(o.LocationWorkspace)
+	if _, err := o.supportedAPIExports(ctx, locationClient); err != nil {
+		issues = append(issues, err.Error())
+	}
 
-// Validate validates the BindOptions are complete and usable.
-func (o *BindComputeOptions) Validate() error {
-	return nil
+	locations, err := locationClient.SchedulingV1alpha1().Locations().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return append(issues, fmt.Sprintf("failed to list locations in workspace %s: %v", o.LocationWorkspace, err))
+	}
+	for i, selector := range o.locationSelectors {
+		placement := &schedulingv1alpha1.Placement{Spec: o.placementSpec()}
+		placement.Spec.LocationSelectors = []metav1.LabelSelector{selector}
+		if selectedLocationNames(placement, locations.Items).Len() == 0 {
+			issues = append(issues, fmt.Sprintf("location selector %q matches no locations in workspace %s", o.LocationSelectorsStrings[i], o.LocationWorkspace))
+		}
+	}
+	return issues
 }
 
-// Run creates a placement in the workspace, linking to the location workspace
-func (o *BindComputeOptions) Run(ctx context.Context) error {
+// sourceAnnotations returns the annotations recording the version of bind compute and the user running it. The user
+// is only recorded if the kubeconfig tells it, through impersonation or basic authentication.
+func (o *BindComputeOptions) sourceAnnotations() (map[string]string, error) {
 	config, err := o.ClientConfig.ClientConfig()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	userWorkspaceKcpClient, err := kcpclient.NewForConfig(config)
-	if err != nil {
-		return fmt.Errorf("failed to create kcp client: %w", err)
+
+	annotations := map[string]string{
+		SourceVersionAnnotationKey: "kcp-bind-compute/" + version.Get().GitVersion,
+	}
+	user := config.Impersonate.UserName
+	if len(user) == 0 {
+		user = config.Username
+	}
+	if len(user) > 0 {
+		annotations[SourceUserAnnotationKey] = user
+	}
+	return annotations, nil
+}
+
+// printLocationWorkspaceType prints the type of the location workspace, to confirm the placement targets the intended
+// kind of workspace. Failing to read the workspace is reported, but is not an error.
+func (o *BindComputeOptions) printLocationWorkspaceType(ctx context.Context, kcpClient kcpclient.ClusterInterface) error {
+	parentClusterName, workspaceName := o.LocationWorkspace.Split()
+	if parentClusterName.Empty() {
+		return nil
 	}
 
-	// build config to connect to location workspace
-	kcpConfig := rest.CopyConfig(config)
-	url, _, err := helpers.ParseClusterURL(config.Host)
+	workspace, err := kcpClient.Cluster(parentClusterName).TenancyV1beta1().Workspaces().Get(ctx, workspaceName, metav1.GetOptions{})
 	if err != nil {
+		_, err := fmt.Fprintf(o.Out, "location workspace %s: type unknown: %v\n", o.LocationWorkspace, err)
 		return err
 	}
 
-	kcpConfig.Host = url.String()
-	kcpClient, err := kcpclient.NewClusterForConfig(kcpConfig)
-	if err != nil {
-		return fmt.Errorf("failed to create kcp client: %w", err)
+	workspaceType := string(workspace.Spec.Type.Name)
+	if len(workspace.Spec.Type.Path) > 0 {
+		workspaceType = logicalcluster.New(workspace.Spec.Type.Path).Join(workspaceType).String()
 	}
+	_, err = fmt.Fprintf(o.Out, "location workspace %s: type %s\n", o.LocationWorkspace, workspaceType)
+	return err
+}
 
-	supportedExports, err := o.supportedAPIExports(ctx, kcpClient.Cluster(o.LocationWorkspace))
-	if err != nil {
+// printSelectedLocations prints the location selected by the placement. If the placement status does not record a
+// selected location, the locations matching the placement's selectors are printed instead.
+func (o *BindComputeOptions) printSelectedLocations(ctx context.Context, locationClient kcpclient.Interface, placement *schedulingv1alpha1.Placement) error {
+	if selected := placement.Status.SelectedLocation; selected != nil {
+		_, err := fmt.Fprintf(o.Out, "placement %s selected location %s in workspace %s.\n", placement.Name, selected.LocationName, selected.Path)
 		return err
 	}
 
-	bindings, err := o.applyAPIBinding(ctx, userWorkspaceKcpClient, supportedExports)
+	locations, err := locationClient.SchedulingV1alpha1().Locations().List(ctx, metav1.ListOptions{})
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to list locations in workspace %s: %w", o.LocationWorkspace, err)
 	}
 
-	placement, err := o.applyPlacement(ctx, userWorkspaceKcpClient)
+	selected := selectedLocationNames(placement, locations.Items)
+	_, err = fmt.Fprintf(o.Out, "placement %s matches locations in workspace %s: %s\n", placement.Name, o.LocationWorkspace, strings.Join(selected.List(), ","))
+	return err
+}
+
+// matchedLocations returns the Locations of the location workspace selected by any of the placements, sorted by name.
+func (o *BindComputeOptions) matchedLocations(ctx context.Context, locationClient kcpclient.Interface, placements []*schedulingv1alpha1.Placement) ([]runtime.Object, error) {
+	locations, err := locationClient.SchedulingV1alpha1().Locations().List(ctx, metav1.ListOptions{})
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to list locations in workspace %s: %w", o.LocationWorkspace, err)
 	}
 
-	// wait for bind to be ready
-	if !bindReady(bindings, placement) {
-		if err := wait.PollImmediate(time.Millisecond*500, o.BindWaitTimeout, func() (done bool, err error) {
-			currentPlacement, err := userWorkspaceKcpClient.SchedulingV1alpha1().Placements().Get(ctx, placement.Name, metav1.GetOptions{})
-			if err != nil {
-				return false, err
-			}
-			var currentBindings []*apisv1alpha1.APIBinding
-			for _, binding := range bindings {
-				currentBinding, err := userWorkspaceKcpClient.ApisV1alpha1().APIBindings().Get(ctx, binding.Name, metav1.GetOptions{})
-				if err != nil {
-					return false, err
-				}
-				currentBindings = append(currentBindings, currentBinding)
-			}
+	selected := sets.NewString()
+	for _, placement := range placements {
+		selected.Insert(selectedLocationNames(placement, locations.Items).UnsortedList()...)
+	}
+	sort.Slice(locations.Items, func(i, j int) bool {
+		return locations.Items[i].Name < locations.Items[j].Name
+	})
+
+	var objs []runtime.Object
+	for i := range locations.Items {
+		if selected.Has(locations.Items[i].Name) {
+			objs = append(objs, &locations.Items[i])
+		}
+	}
+	return objs, nil
+}
+
+// printSelectorPreview prints the locations in the location workspace matched by each of the location selectors, and
+// by the placement as a whole.
+func (o *BindComputeOptions) printSelectorPreview(ctx context.Context, locationClient kcpclient.Interface) error {
+	locations, err := locationClient.SchedulingV1alpha1().Locations().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list locations in workspace %s: %w", o.LocationWorkspace, err)
+	}
 
-			return bindReady(currentBindings, currentPlacement), nil
-		}); err != nil {
-			return fmt.Errorf("bind compute is not ready %s: %w", placement.Name, err)
+	placement := &schedulingv1alpha1.Placement{Spec: o.placementSpec()}
+	for i, selector := range o.locationSelectors {
+		selectorPlacement := placement.DeepCopy()
+		selectorPlacement.Spec.LocationSelectors = []metav1.LabelSelector{selector}
+		selected := selectedLocationNames(selectorPlacement, locations.Items)
+		if _, err := fmt.Fprintf(o.Out, "location selector %q matches %d location(s): %s\n", o.LocationSelectorsStrings[i], selected.Len(), strings.Join(selected.List(), ",")); err != nil {
+			return err
 		}
 	}
 
-	return nil
+	selected := selectedLocationNames(placement, locations.Items)
+	_, err = fmt.Fprintf(o.Out, "placement would select from %d location(s) in workspace %s: %s\n", selected.Len(), o.LocationWorkspace, strings.Join(selected.List(), ","))
+	return err
 }
 
-func bindReady(bindings []*apisv1alpha1.APIBinding, placement *schedulingv1alpha1.Placement) bool {
-	if !conditions.IsTrue(placement, schedulingv1alpha1.PlacementReady) {
+// bindReady returns whether the bindings are bound and the placement is ready, ignoring either part with
+// --wait-placement-only or --wait-bindings-only.
+func (o *BindComputeOptions) bindReady(bindings []*apisv1alpha1.APIBinding, placement *schedulingv1alpha1.Placement) bool {
+	if !o.WaitBindingsOnly && !o.placementReady(placement) {
 		return false
 	}
 
+	if o.WaitPlacementOnly {
+		return true
+	}
 	for _, binding := range bindings {
 		if binding.Status.Phase != apisv1alpha1.APIBindingPhaseBound {
 			return false
@@ -213,104 +1408,302 @@ func bindReady(bindings []*apisv1alpha1.APIBinding, placement *schedulingv1alpha
 	return true
 }
 
-const maxBindingNamePrefixLength = validation.DNS1123SubdomainMaxLength - 1 - 8
+// bindTimings records when the first APIBinding was seen bound while waiting for readiness, for --timings.
+type bindTimings struct {
+	start      time.Time
+	firstBound time.Duration
+}
 
-func apiBindingName(clusterName logicalcluster.Name, apiExportName string) string {
-	maxLen := len(apiExportName)
-	if maxLen > maxBindingNamePrefixLength {
-		maxLen = maxBindingNamePrefixLength
+// observe records the time elapsed since the start of the bind if one of the APIBindings is bound for the first time.
+func (t *bindTimings) observe(bindings []*apisv1alpha1.APIBinding) {
+	if t.firstBound > 0 {
+		return
+	}
+	for _, binding := range bindings {
+		if binding.Status.Phase == apisv1alpha1.APIBindingPhaseBound {
+			t.firstBound = time.Since(t.start)
+			return
+		}
 	}
-	bindingNamePrefix := apiExportName[:maxLen]
-
-	hash := sha256.Sum224([]byte(clusterName.Path()))
-	base36hash := strings.ToLower(base36.EncodeBytes(hash[:]))
-	return fmt.Sprintf("%s-%s", bindingNamePrefix, base36hash[:8])
 }
 
-func (o *BindComputeOptions) applyAPIBinding(ctx context.Context, client kcpclient.Interface, desiredAPIExports sets.String) ([]*apisv1alpha1.APIBinding, error) {
-	apiBindings, err := client.ApisV1alpha1().APIBindings().List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return nil, err
+// String describes the time to the first APIBinding bound, and the given time to readiness.
+func (t *bindTimings) String(ready time.Duration) string {
+	firstBound := "no apibinding bound"
+	if t.firstBound > 0 {
+		firstBound = "first apibinding bound after " + t.firstBound.Round(time.Millisecond*100).String()
 	}
+	return fmt.Sprintf("timings: %s, ready after %s.", firstBound, ready.Round(time.Millisecond*100))
+}
 
-	existingAPIExports := sets.NewString()
-	for _, binding := range apiBindings.Items {
-		if binding.Spec.Reference.Workspace == nil {
-			continue
+// terminalBindingConditions are the reasons of the APIBinding conditions that do not resolve by waiting, when the
+// condition is False.
+var terminalBindingConditions = []struct {
+	conditionType conditionsv1alpha1.ConditionType
+	reasons       sets.String
+}{
+	{apisv1alpha1.APIExportValid, sets.NewString(apisv1alpha1.APIExportNotFoundReason, apisv1alpha1.APIExportInvalidReferenceReason)},
+	{apisv1alpha1.InitialBindingCompleted, sets.NewString(apisv1alpha1.APIResourceSchemaInvalidReason)},
+	{apisv1alpha1.BindingUpToDate, sets.NewString(apisv1alpha1.APIResourceSchemaInvalidReason, apisv1alpha1.NamingConflictsReason)},
+	{apisv1alpha1.PermissionClaimsValid, sets.NewString(apisv1alpha1.InvalidPermissionClaimsReason)},
+}
+
+// terminalPlacementReasons are the reasons of the placement Ready condition that do not resolve by waiting, when it
+// is False: the location selectors match no location, or the selected location cannot be used anymore. A location
+// not found is not terminal, as Locations are created asynchronously for new SyncTargets.
+var terminalPlacementReasons = sets.NewString(schedulingv1alpha1.LocationNotMatchReason, schedulingv1alpha1.LocationInvalidReason)
+
+// terminalFailure returns an error with the failing reason if any of the waited for APIBindings or the placement
+// reports a terminal failure, see terminalBindingConditions and terminalPlacementReasons.
+func (o *BindComputeOptions) terminalFailure(bindings []*apisv1alpha1.APIBinding, placement *schedulingv1alpha1.Placement) error {
+	var errs []error
+	if !o.WaitPlacementOnly {
+		errs = append(errs, terminalBindingFailures(bindings)...)
+	}
+	if !o.WaitBindingsOnly {
+		if reason := conditions.GetReason(placement, schedulingv1alpha1.PlacementReady); conditions.IsFalse(placement, schedulingv1alpha1.PlacementReady) && terminalPlacementReasons.Has(reason) {
+			errs = append(errs, fmt.Errorf("placement %s failed: %s is False with reason %s: %s", placement.Name, schedulingv1alpha1.PlacementReady,
+				reason, conditions.GetMessage(placement, schedulingv1alpha1.PlacementReady)))
 		}
-		existingAPIExports.Insert(fmt.Sprintf("%s:%s", binding.Spec.Reference.Workspace.Path, binding.Spec.Reference.Workspace.ExportName))
 	}
+	return utilerrors.NewAggregate(errs)
+}
 
-	diff := desiredAPIExports.Difference(existingAPIExports)
+// terminalBindingFailures returns an error for each APIBinding that failed for a reason that will not resolve itself.
+func terminalBindingFailures(bindings []*apisv1alpha1.APIBinding) []error {
 	var errs []error
-	var bindings []*apisv1alpha1.APIBinding
-	for export := range diff {
-		clusterName, name := logicalcluster.New(export).Split()
-		apiBinding := &apisv1alpha1.APIBinding{
-			ObjectMeta: metav1.ObjectMeta{
-				Name: apiBindingName(clusterName, name),
-			},
-			Spec: apisv1alpha1.APIBindingSpec{
-				Reference: apisv1alpha1.ExportReference{
-					Workspace: &apisv1alpha1.WorkspaceExportReference{
-						Path:       clusterName.String(),
-						ExportName: name,
-					},
-				},
-			},
-		}
-		binding, err := client.ApisV1alpha1().APIBindings().Create(ctx, apiBinding, metav1.CreateOptions{})
-		if err != nil && !errors.IsAlreadyExists(err) {
-			errs = append(errs, err)
+	for _, binding := range bindings {
+		for _, terminal := range terminalBindingConditions {
+			if conditions.IsFalse(binding, terminal.conditionType) && terminal.reasons.Has(conditions.GetReason(binding, terminal.conditionType)) {
+				errs = append(errs, fmt.Errorf("apibinding %s failed: %s is False with reason %s: %s", binding.Name, terminal.conditionType,
+					conditions.GetReason(binding, terminal.conditionType), conditions.GetMessage(binding, terminal.conditionType)))
+			}
+		}
+	}
+	return errs
+}
+
+// placementReady returns whether the placement is Ready, and the conditions of --require-condition are True.
+func (o *BindComputeOptions) placementReady(placement *schedulingv1alpha1.Placement) bool {
+	if !conditions.IsTrue(placement, schedulingv1alpha1.PlacementReady) {
+		return false
+	}
+	for _, conditionType := range o.RequiredConditions {
+		if !conditions.IsTrue(placement, conditionsv1alpha1.ConditionType(conditionType)) {
+			return false
 		}
+	}
+	return true
+}
+
+// IsRetryableError returns whether running the bind again might succeed after it failed with the given error, i.e.
+// the error is transient, like a network error or an unavailable server, rather than caused by the options, the
+// state of the workspaces, or the bind running out of --timeout or --deadline.
+func IsRetryableError(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	// the bind is not given more time than --timeout and --deadline allow.
+	if errors.Is(err, wait.ErrWaitTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
 
-		bindings = append(bindings, binding)
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
 
-		_, err = fmt.Fprintf(o.Out, "apibinding %s for apiexport %s created.\n", apiBinding.Name, export)
-		if err != nil {
-			errs = append(errs, err)
+	if agg, ok := err.(utilerrors.Aggregate); ok {
+		for _, err := range agg.Errors() {
+			if IsRetryableError(err) {
+				return true
+			}
 		}
+		return false
 	}
 
-	return bindings, utilerrors.NewAggregate(errs)
+	return apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err) || apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServiceUnavailable(err) || apierrors.IsInternalError(err) || apierrors.IsUnexpectedServerError(err)
 }
 
-func (o *BindComputeOptions) applyPlacement(ctx context.Context, client kcpclient.Interface) (*schedulingv1alpha1.Placement, error) {
-	placement := &schedulingv1alpha1.Placement{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: o.PlacementName,
-		},
-		Spec: schedulingv1alpha1.PlacementSpec{
-			NamespaceSelector: o.namespaceSelector,
-			LocationSelectors: o.locationSelectors,
-			LocationWorkspace: o.LocationWorkspace.String(),
-			LocationResource: schedulingv1alpha1.GroupVersionResource{
-				Group:    "workload.kcp.dev",
-				Version:  "v1alpha1",
-				Resource: "synctargets",
-			},
-		},
+// NoSyncTargetsError is returned when the location workspace does not contain any SyncTarget, so no APIExport can be
+// supported.
+type NoSyncTargetsError struct {
+	LocationWorkspace logicalcluster.Name
+	// Selector is the label selector the SyncTargets were listed with, if any.
+	Selector string
+}
+
+func (e *NoSyncTargetsError) Error() string {
+	if len(e.Selector) > 0 {
+		return fmt.Sprintf("location workspace %s has no SyncTargets matching %q", e.LocationWorkspace, e.Selector)
 	}
+	return fmt.Sprintf("location workspace %s has no SyncTargets; register a SyncTarget first, e.g. with \"kubectl kcp workload sync\"", e.LocationWorkspace)
+}
 
-	placement, err := client.SchedulingV1alpha1().Placements().Create(ctx, placement, metav1.CreateOptions{})
-	if err != nil && !errors.IsAlreadyExists(err) {
-		return nil, err
+// PartialBindExitCode is the exit code of a bind that only partially succeeded with --continue-on-binding-error.
+const PartialBindExitCode = 2
+
+// PartialBindError is returned with --continue-on-binding-error when some APIBindings could not be created, while the
+// bind went on with the others.
+type PartialBindError struct {
+	// Bound is the number of APIBindings created or already existing.
+	Bound  int
+	Errors []error
+}
+
+func (e *PartialBindError) Error() string {
+	return fmt.Sprintf("partially bound: %d apibinding(s) bound, %d failed to be created: %v", e.Bound, len(e.Errors), utilerrors.NewAggregate(e.Errors))
+}
+
+// ExitCode returns the exit code telling a partial success from a failure.
+func (e *PartialBindError) ExitCode() int {
+	return PartialBindExitCode
+}
+
+// listPageSize is the number of objects requested per page when listing SyncTargets and APIBindings.
+const listPageSize = 500
+
+// listAPIBindings lists all APIBindings in the workspace, following continue tokens until the last page.
+func listAPIBindings(ctx context.Context, client kcpclient.Interface) ([]apisv1alpha1.APIBinding, error) {
+	var apiBindings []apisv1alpha1.APIBinding
+	opts := metav1.ListOptions{Limit: listPageSize}
+	for {
+		list, err := client.ApisV1alpha1().APIBindings().List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		apiBindings = append(apiBindings, list.Items...)
+		if len(list.Continue) == 0 {
+			return apiBindings, nil
+		}
+		opts.Continue = list.Continue
+	}
+}
+
+// listSyncTargets lists all SyncTargets in the workspace matching the label selector, following continue tokens until
+// the last page.
+func listSyncTargets(ctx context.Context, client kcpclient.Interface, selector string) ([]workloadv1alpha1.SyncTarget, error) {
+	var syncTargets []workloadv1alpha1.SyncTarget
+	opts := metav1.ListOptions{LabelSelector: selector, Limit: listPageSize}
+	for {
+		list, err := client.WorkloadV1alpha1().SyncTargets().List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		syncTargets = append(syncTargets, list.Items...)
+		if len(list.Continue) == 0 {
+			return syncTargets, nil
+		}
+		opts.Continue = list.Continue
 	}
+}
+
+// exportReferenceKey returns the <workspace_path>:<apiexport> form of the given reference, matching the format
+// of the exports passed via --apiexports.
+func exportReferenceKey(reference *apisv1alpha1.WorkspaceExportReference) string {
+	return logicalcluster.New(reference.Path).Join(reference.ExportName).String()
+}
 
-	_, err = fmt.Fprintf(o.Out, "placement %s created.\n", placement.Name)
-	return placement, err
+// formatLocationResource formats the location resource as <resource>.<version>.<group>.
+func formatLocationResource(resource schedulingv1alpha1.GroupVersionResource) string {
+	return fmt.Sprintf("%s.%s.%s", resource.Resource, resource.Version, resource.Group)
 }
 
 func (o *BindComputeOptions) supportedAPIExports(ctx context.Context, client kcpclient.Interface) (sets.String, error) {
 	currentExports := sets.NewString(o.APIExports...)
 
-	syncTargets, err := client.WorkloadV1alpha1().SyncTargets().List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return currentExports, err
+	var syncTargets []workloadv1alpha1.SyncTarget
+	if len(o.FromSyncTarget) > 0 {
+		syncTarget, err := client.WorkloadV1alpha1().SyncTargets().Get(ctx, o.FromSyncTarget, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return currentExports, fmt.Errorf("synctarget %s not found in workspace %s", o.FromSyncTarget, o.LocationWorkspace)
+		}
+		if err != nil {
+			return currentExports, err
+		}
+		syncTargets = append(syncTargets, *syncTarget)
+	} else {
+		var err error
+		if syncTargets, err = listSyncTargets(ctx, client, o.SyncTargetSelector); err != nil {
+			return currentExports, err
+		}
+		if len(syncTargets) == 0 {
+			return currentExports, &NoSyncTargetsError{LocationWorkspace: o.LocationWorkspace, Selector: o.SyncTargetSelector}
+		}
+	}
+
+	supportedExports := syncTargetsAPIExports(syncTargets, o.LocationWorkspace)
+
+	// if apiexports is not specified, check if synctargets support global/local kubernetes APIExport and add them.
+	if currentExports.Len() == 0 {
+		kubernetesAPIExportName := o.kubernetesAPIExportName()
+		for _, export := range o.defaultAPIExports(o.LocationWorkspace) {
+			if supportedExports.Has(export) {
+				currentExports.Insert(export)
+			}
+		}
+		if currentExports.Len() == 0 && len(o.KubernetesVersion) > 0 {
+			return currentExports, fmt.Errorf("APIExport %s is not supported by the synctargets in workspace %s, neither in root:compute nor in the location workspace", kubernetesAPIExportName, o.LocationWorkspace)
+		}
+	} else {
+		diff := currentExports.Difference(supportedExports)
+		if diff.Len() > 0 && o.IgnoreUnsupported {
+			if err := o.warn("skipping APIExports not supported by any synctarget in workspace %s: %s", o.LocationWorkspace, strings.Join(diff.List(), ",")); err != nil {
+				return currentExports, err
+			}
+			currentExports = currentExports.Intersection(supportedExports)
+		} else if diff.Len() > 0 {
+			return currentExports, fmt.Errorf("the following APIExports are not supported by the synctargets in workspace %s: %s", o.LocationWorkspace, strings.Join(diff.List(), ","))
+		}
+	}
+
+	if o.Refresh {
+		currentExports.Insert(supportedExports.UnsortedList()...)
+	}
+
+	// the APIExports of --apiexports-from-workspace are bound as far as they can be served.
+	if unsupported := o.workspaceAPIExports.Difference(supportedExports); unsupported.Len() > 0 {
+		if err := o.warn("skipping APIExports of workspace %s not supported by any synctarget in workspace %s: %s", o.apiExportsFromWorkspace, o.LocationWorkspace, strings.Join(unsupported.List(), ",")); err != nil {
+			return currentExports, err
+		}
+	}
+	currentExports.Insert(o.workspaceAPIExports.Intersection(supportedExports).UnsortedList()...)
+
+	excluded := sets.NewString(o.ExcludeAPIExports...)
+	if missing := excluded.Difference(currentExports); missing.Len() > 0 && !o.IgnoreMissingExcludes {
+		return currentExports, fmt.Errorf("the following excluded APIExports are not among the APIExports to bind: %s", strings.Join(missing.List(), ","))
+	}
+	currentExports = currentExports.Difference(excluded)
+
+	return currentExports, nil
+}
+
+// listWorkspaceAPIExports returns all APIExports of the given workspace, as <workspace_path>:<apiexport>.
+func listWorkspaceAPIExports(ctx context.Context, client kcpclient.Interface, workspace logicalcluster.Name) (sets.String, error) {
+	exports := sets.NewString()
+	opts := metav1.ListOptions{Limit: listPageSize}
+	for {
+		list, err := client.ApisV1alpha1().APIExports().List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list apiexports in workspace %s: %w", workspace, err)
+		}
+		for _, apiExport := range list.Items {
+			exports.Insert(workspace.Join(apiExport.Name).String())
+		}
+		if len(list.Continue) == 0 {
+			return exports, nil
+		}
+		opts.Continue = list.Continue
 	}
+}
 
+// syncTargetsAPIExports returns the APIExports supported by any of the SyncTargets of the location workspace, as
+// <workspace_path>:<apiexport>.
+func syncTargetsAPIExports(syncTargets []workloadv1alpha1.SyncTarget, locationWorkspace logicalcluster.Name) sets.String {
 	supportedExports := sets.NewString()
-	for _, syncTarget := range syncTargets.Items {
+	for _, syncTarget := range syncTargets {
 		for _, apiExport := range syncTarget.Spec.SupportedAPIExports {
 			if apiExport.Workspace == nil {
 				continue
@@ -319,29 +1712,92 @@ func (o *BindComputeOptions) supportedAPIExports(ctx context.Context, client kcp
 			path := apiExport.Workspace.Path
 			// if path is not set, the apiexport is in the location workspace
 			if len(path) == 0 {
-				path = o.LocationWorkspace.String()
+				path = locationWorkspace.String()
 			}
 			supportedExports.Insert(fmt.Sprintf("%s:%s", path, apiExport.Workspace.ExportName))
 		}
 	}
+	return supportedExports
+}
 
-	// if apiexports is not specified, check if synctargets support global/local kubernetes APIExport and add them.
-	if currentExports.Len() == 0 {
-		defaultAPIExports := []string{
-			"root:compute:kubernetes",
-			o.LocationWorkspace.String() + ":kubernetes",
+// defaultAPIExports returns the kubernetes APIExports bound when no APIExports are given, either global or local to
+// the location workspace.
+func (o *BindComputeOptions) defaultAPIExports(locationWorkspace logicalcluster.Name) []string {
+	kubernetesAPIExportName := o.kubernetesAPIExportName()
+	return []string{
+		"root:compute:" + kubernetesAPIExportName,
+		locationWorkspace.Join(kubernetesAPIExportName).String(),
+	}
+}
+
+// kubernetesAPIExportName returns the name of the kubernetes APIExport bound by default.
+func (o *BindComputeOptions) kubernetesAPIExportName() string {
+	if len(o.KubernetesVersion) == 0 {
+		return "kubernetes"
+	}
+	return "kubernetes-" + o.KubernetesVersion
+}
+
+// checkExclusiveLocations warns, or fails with --strict, if other placements in the workspace select any of the
+// locations selected by the given placement.
+func (o *BindComputeOptions) checkExclusiveLocations(ctx context.Context, client kcpclient.Interface, locationClient kcpclient.Interface, placement *schedulingv1alpha1.Placement) error {
+	locations, err := locationClient.SchedulingV1alpha1().Locations().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list locations in workspace %s: %w", o.LocationWorkspace, err)
+	}
+
+	placements, err := client.SchedulingV1alpha1().Placements().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	selected := selectedLocationNames(placement, locations.Items)
+
+	var errs []error
+	for i := range placements.Items {
+		other := &placements.Items[i]
+		if other.Name == placement.Name || other.Spec.LocationWorkspace != placement.Spec.LocationWorkspace {
+			continue
 		}
-		for _, export := range defaultAPIExports {
-			if supportedExports.Has(export) {
-				currentExports.Insert(export)
-			}
+
+		overlap := selected.Intersection(selectedLocationNames(other, locations.Items))
+		if overlap.Len() == 0 {
+			continue
 		}
-	} else {
-		diff := currentExports.Difference(supportedExports)
-		if diff.Len() > 0 {
-			return currentExports, fmt.Errorf("the following APIExports are not supported by the synctargets in workspace %s: %s", o.LocationWorkspace, strings.Join(diff.List(), ","))
+
+		msg := fmt.Sprintf("placement %s selects locations also selected by placement %s: %s", placement.Name, other.Name, strings.Join(overlap.List(), ","))
+		if o.Strict {
+			errs = append(errs, errors.New(msg))
+			continue
+		}
+		if err := o.warn("%s", msg); err != nil {
+			errs = append(errs, err)
 		}
 	}
 
-	return currentExports, nil
+	return utilerrors.NewAggregate(errs)
+}
+
+// selectedLocationNames returns the names of the locations matching any of the placement's location selectors, the
+// same way the placement scheduler evaluates them.
+func selectedLocationNames(placement *schedulingv1alpha1.Placement, locations []schedulingv1alpha1.Location) sets.String {
+	selected := sets.NewString()
+	for _, location := range locations {
+		if location.Spec.Resource != placement.Spec.LocationResource {
+			continue
+		}
+
+		for i := range placement.Spec.LocationSelectors {
+			selector, err := metav1.LabelSelectorAsSelector(&placement.Spec.LocationSelectors[i])
+			if err != nil {
+				// skip this selector
+				continue
+			}
+
+			if selector.Matches(labels.Set(location.Labels)) {
+				selected.Insert(location.Name)
+			}
+		}
+	}
+	return selected
 }
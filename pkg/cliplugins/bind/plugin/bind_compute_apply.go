@@ -0,0 +1,531 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/martinlindhe/base36"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+)
+
+// replacePlacement deletes the existing placement of the same name as the given one, waits for it to be gone, and
+// creates the given one instead.
+func (o *BindComputeOptions) replacePlacement(ctx context.Context, client kcpclient.Interface, placement *schedulingv1alpha1.Placement) (*schedulingv1alpha1.Placement, error) {
+	if !o.Yes {
+		confirmed, err := confirm(o.IOStreams, fmt.Sprintf("Placement %s exists with different selectors. Delete and create it again?", placement.Name))
+		if err != nil {
+			return nil, err
+		}
+		if !confirmed {
+			return nil, fmt.Errorf("placement %s exists with different selectors, and replacing it was not confirmed", placement.Name)
+		}
+	}
+
+	if err := client.SchedulingV1alpha1().Placements().Delete(ctx, placement.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	if err := o.pollUntilReady(ctx, func(ctx context.Context) (bool, error) {
+		_, err := client.SchedulingV1alpha1().Placements().Get(ctx, placement.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}); err != nil {
+		return nil, fmt.Errorf("placement %s is still being deleted: %w", placement.Name, err)
+	}
+
+	return client.SchedulingV1alpha1().Placements().Create(ctx, placement, metav1.CreateOptions{})
+}
+
+// idempotencyKey returns a key derived from the name and spec of the placement, identifying the create request.
+func idempotencyKey(placement *schedulingv1alpha1.Placement) (string, error) {
+	spec, err := json.Marshal(placement.Spec)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum224(append([]byte(placement.Name+"/"), spec...))
+	return strings.ToLower(base36.EncodeBytes(hash[:])), nil
+}
+
+const maxBindingNamePrefixLength = validation.DNS1123SubdomainMaxLength - 1 - 8
+
+// bindingNameStrategies are the naming schemes of the APIBindings selectable with --binding-name-strategy, from the
+// workspace path and the name of the APIExport. Only hash is free of collisions, the others are checked for them.
+var bindingNameStrategies = map[string]func(clusterName logicalcluster.Name, apiExportName string) string{
+	"hash": apiBindingName,
+	"export-name": func(_ logicalcluster.Name, apiExportName string) string {
+		return apiExportName
+	},
+	"export-name-workspace": func(clusterName logicalcluster.Name, apiExportName string) string {
+		return apiExportName + "-" + strings.ReplaceAll(clusterName.String(), ":", "-")
+	},
+}
+
+// bindingName returns the name of the APIBinding to create for the given APIExport, following --binding-name-strategy.
+func (o *BindComputeOptions) bindingName(clusterName logicalcluster.Name, apiExportName string) string {
+	if strategy, ok := bindingNameStrategies[o.BindingNameStrategy]; ok {
+		return strategy(clusterName, apiExportName)
+	}
+	return apiBindingName(clusterName, apiExportName)
+}
+
+// checkBindingNames checks that the names of the APIBindings of the given APIExports are valid and distinct, which
+// only the hash strategy guarantees.
+func (o *BindComputeOptions) checkBindingNames(exports sets.String) error {
+	var errs []error
+	exportsByName := map[string]string{}
+	for _, export := range exports.List() {
+		name := o.exportBindingName(export)
+		if msgs := validation.IsDNS1123Subdomain(name); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("invalid apibinding name %q for apiexport %s with --binding-name-strategy=%s: %s", name, export, o.BindingNameStrategy, strings.Join(msgs, ", ")))
+			continue
+		}
+		if other, ok := exportsByName[name]; ok {
+			errs = append(errs, fmt.Errorf("apiexports %s and %s would both be bound by apibinding %s with --binding-name-strategy=%s", other, export, name, o.BindingNameStrategy))
+			continue
+		}
+		exportsByName[name] = export
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func apiBindingName(clusterName logicalcluster.Name, apiExportName string) string {
+	maxLen := len(apiExportName)
+	if maxLen > maxBindingNamePrefixLength {
+		maxLen = maxBindingNamePrefixLength
+	}
+	bindingNamePrefix := apiExportName[:maxLen]
+
+	hash := sha256.Sum224([]byte(clusterName.Path()))
+	base36hash := strings.ToLower(base36.EncodeBytes(hash[:]))
+	return fmt.Sprintf("%s-%s", bindingNamePrefix, base36hash[:8])
+}
+
+// applyAPIBinding ensures an APIBinding exists for each of the desired APIExports. New APIBindings accept the given
+// permission claims, indexed by APIExport.
+func (o *BindComputeOptions) applyAPIBinding(ctx context.Context, client kcpclient.Interface, desiredAPIExports sets.String, permissionClaims map[string][]apisv1alpha1.AcceptablePermissionClaim) ([]*apisv1alpha1.APIBinding, error) {
+	apiBindings, err := listAPIBindings(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	// index existing bindings by the export they reference rather than by object name. Bindings created before
+	// the location workspace was moved carry a name derived from the old path, but still bind the same export.
+	existingBindings := map[string]*apisv1alpha1.APIBinding{}
+	existingAPIExports := sets.NewString()
+	for i := range apiBindings {
+		binding := &apiBindings[i]
+		if binding.Spec.Reference.Workspace == nil {
+			continue
+		}
+		export := exportReferenceKey(binding.Spec.Reference.Workspace)
+		existingBindings[export] = binding
+		existingAPIExports.Insert(export)
+	}
+
+	if err := o.checkBindingNames(desiredAPIExports.Difference(existingAPIExports)); err != nil {
+		return nil, err
+	}
+
+	var errs, createErrs []error
+	var bindings []*apisv1alpha1.APIBinding
+	// with --compact, the names are printed once per action at the end.
+	compactNames := map[string][]string{}
+	printAction := func(bindingName, export, action string) {
+		if o.Compact {
+			compactNames[action] = append(compactNames[action], bindingName)
+			return
+		}
+		if _, err := fmt.Fprintf(o.Out, "apibinding %s for apiexport %s %s.\n", bindingName, export, action); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for export := range desiredAPIExports.Intersection(existingAPIExports) {
+		bindings = append(bindings, existingBindings[export])
+	}
+
+	// create in the order of the binding names, so that the output is the same on every run.
+	diff := desiredAPIExports.Difference(existingAPIExports)
+	for _, export := range o.sortedByBindingName(diff) {
+		clusterName, name := logicalcluster.New(export).Split()
+		apiBinding := &apisv1alpha1.APIBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            o.bindingName(clusterName, name),
+				Annotations:     o.annotations,
+				OwnerReferences: o.ownerReferences,
+			},
+			Spec: apisv1alpha1.APIBindingSpec{
+				Reference: apisv1alpha1.ExportReference{
+					Workspace: &apisv1alpha1.WorkspaceExportReference{
+						Path:       clusterName.String(),
+						ExportName: name,
+					},
+				},
+				PermissionClaims: permissionClaims[export],
+			},
+		}
+		if o.ShowCommands {
+			if err := o.printCommand(apiBinding); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		var binding *apisv1alpha1.APIBinding
+		var err error
+		action := "created"
+		if o.ServerSideApply {
+			action = "applied"
+			var data []byte
+			if data, err = applyPatch(apiBinding); err == nil {
+				binding, err = client.ApisV1alpha1().APIBindings().Patch(ctx, apiBinding.Name, types.ApplyPatchType, data, metav1.PatchOptions{FieldManager: o.FieldManager})
+			}
+		} else {
+			binding, err = client.ApisV1alpha1().APIBindings().Create(ctx, apiBinding, metav1.CreateOptions{})
+		}
+		if apierrors.IsAlreadyExists(err) {
+			// created concurrently since we listed, or the name is taken by a binding to another export.
+			action = "already exists"
+			binding, err = client.ApisV1alpha1().APIBindings().Get(ctx, apiBinding.Name, metav1.GetOptions{})
+			if err == nil && (binding.Spec.Reference.Workspace == nil || exportReferenceKey(binding.Spec.Reference.Workspace) != export) {
+				err = fmt.Errorf("apibinding %s already exists but does not reference apiexport %s", apiBinding.Name, export)
+			}
+		}
+		if err != nil && o.ContinueOnBindingError {
+			createErrs = append(createErrs, fmt.Errorf("apibinding %s for apiexport %s: %w", apiBinding.Name, export, err))
+			if err := o.warn("failed to create apibinding %s for apiexport %s, continuing: %v", apiBinding.Name, export, err); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		} else if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		bindings = append(bindings, binding)
+		printAction(apiBinding.Name, export, action)
+	}
+
+	if o.Prune {
+		for _, export := range o.sortedByBindingName(existingAPIExports.Difference(desiredAPIExports)) {
+			binding := existingBindings[export]
+			// only delete bindings following the bind compute naming scheme, others were not created by us.
+			clusterName, name := logicalcluster.New(export).Split()
+			if binding.Name != o.bindingName(clusterName, name) {
+				continue
+			}
+
+			if err := client.ApisV1alpha1().APIBindings().Delete(ctx, binding.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				errs = append(errs, err)
+				continue
+			}
+
+			printAction(binding.Name, export, "deleted")
+		}
+	}
+
+	for _, action := range []struct{ name, format string }{
+		{"created", "created %d apibinding(s): %s\n"},
+		{"applied", "applied %d apibinding(s): %s\n"},
+		{"already exists", "%d apibinding(s) already exist: %s\n"},
+		{"deleted", "deleted %d apibinding(s): %s\n"},
+	} {
+		if names := compactNames[action.name]; len(names) > 0 {
+			if _, err := fmt.Fprintf(o.Out, action.format, len(names), strings.Join(names, ", ")); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	sort.Slice(bindings, func(i, j int) bool {
+		return bindings[i].Name < bindings[j].Name
+	})
+	if len(errs) == 0 && len(createErrs) > 0 {
+		return bindings, &PartialBindError{Bound: len(bindings), Errors: createErrs}
+	}
+	return bindings, utilerrors.NewAggregate(append(errs, createErrs...))
+}
+
+// checkExportDrift re-reads the APIBindings once ready, and warns about the requested APIExports none of them
+// references, and about the APIExports they reference that were not requested, e.g. because the reference was
+// rewritten server-side. The APIBindings as re-read are returned.
+func (o *BindComputeOptions) checkExportDrift(ctx context.Context, client kcpclient.Interface, requested sets.String, bindings []*apisv1alpha1.APIBinding) ([]*apisv1alpha1.APIBinding, error) {
+	var current []*apisv1alpha1.APIBinding
+	var warnings []string
+	bound := sets.NewString()
+	for _, binding := range bindings {
+		binding, err := client.ApisV1alpha1().APIBindings().Get(ctx, binding.Name, metav1.GetOptions{})
+		if err != nil {
+			return bindings, err
+		}
+		current = append(current, binding)
+
+		if binding.Spec.Reference.Workspace == nil {
+			warnings = append(warnings, fmt.Sprintf("apibinding %s does not reference an apiexport by workspace anymore", binding.Name))
+			continue
+		}
+		export := exportReferenceKey(binding.Spec.Reference.Workspace)
+		bound.Insert(export)
+		if !requested.Has(export) {
+			warnings = append(warnings, fmt.Sprintf("apibinding %s references apiexport %s, which was not requested", binding.Name, export))
+		}
+	}
+	for _, export := range requested.Difference(bound).List() {
+		warnings = append(warnings, fmt.Sprintf("apiexport %s was requested, but no apibinding references it", export))
+	}
+	for _, warning := range warnings {
+		if err := o.warn("%s", warning); err != nil {
+			return current, err
+		}
+	}
+	return current, nil
+}
+
+// sortedByBindingName returns the given APIExports sorted by the name of the APIBinding bind compute creates for them.
+func (o *BindComputeOptions) sortedByBindingName(exports sets.String) []string {
+	sorted := exports.List()
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return o.exportBindingName(sorted[i]) < o.exportBindingName(sorted[j])
+	})
+	return sorted
+}
+
+// exportBindingName returns the name of the APIBinding bind compute creates for the given <workspace_path>:<apiexport>.
+func (o *BindComputeOptions) exportBindingName(export string) string {
+	clusterName, name := logicalcluster.New(export).Split()
+	return o.bindingName(clusterName, name)
+}
+
+// verifyExportEndpoints warns about the given APIExports that cannot be read, or whose virtual workspace endpoints
+// are not ready.
+func (o *BindComputeOptions) verifyExportEndpoints(ctx context.Context, kcpClient kcpclient.ClusterInterface, exports sets.String) error {
+	for _, export := range exports.List() {
+		clusterName, name := logicalcluster.New(export).Split()
+		apiExport, err := kcpClient.Cluster(clusterName).ApisV1alpha1().APIExports().Get(ctx, name, metav1.GetOptions{})
+		var warning string
+		switch {
+		case err != nil:
+			warning = fmt.Sprintf("cannot verify the endpoints of apiexport %s: %v", export, err)
+		case !exportEndpointsReady(apiExport):
+			warning = fmt.Sprintf("apiexport %s has no ready endpoints, bindings to it might not be served yet", export)
+		default:
+			continue
+		}
+		if err := o.warn("%s", warning); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyExportIdentities checks the identity hash of the APIExports given with --expect-identity, and refuses to bind
+// any of them if one does not match.
+func (o *BindComputeOptions) verifyExportIdentities(ctx context.Context, kcpClient kcpclient.ClusterInterface, exports sets.String) error {
+	expected := map[string]string{}
+	for export, hash := range o.expectedIdentities {
+		if len(export) == 0 {
+			// a bare hash applies to the only APIExport bound.
+			if exports.Len() != 1 {
+				return fmt.Errorf("--expect-identity without an APIExport requires binding a single APIExport, got %d: %s", exports.Len(), strings.Join(exports.List(), ","))
+			}
+			export = exports.List()[0]
+		}
+		if !exports.Has(export) {
+			return fmt.Errorf("expected identity given for apiexport %s, which is not bound", export)
+		}
+		expected[export] = hash
+	}
+
+	var errs []error
+	for _, export := range sets.StringKeySet(expected).List() {
+		clusterName, name := logicalcluster.New(export).Split()
+		apiExport, err := kcpClient.Cluster(clusterName).ApisV1alpha1().APIExports().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get apiexport %s to verify its identity: %w", export, err))
+			continue
+		}
+		if apiExport.Status.IdentityHash != expected[export] {
+			errs = append(errs, fmt.Errorf("refusing to bind apiexport %s: its identity hash %q does not match the expected %q", export, apiExport.Status.IdentityHash, expected[export]))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// exportEndpointsReady returns whether the APIExport has virtual workspace URLs, and reports them as ready.
+func exportEndpointsReady(apiExport *apisv1alpha1.APIExport) bool {
+	return len(apiExport.Status.VirtualWorkspaces) > 0 && conditions.IsTrue(apiExport, apisv1alpha1.APIExportVirtualWorkspaceURLsReady)
+}
+
+// permissionClaimsToAccept returns the permission claims to accept on the APIBinding of each of the given
+// APIExports, as requested with --accept-permission-claims.
+func (o *BindComputeOptions) permissionClaimsToAccept(ctx context.Context, kcpClient kcpclient.ClusterInterface, exports sets.String) (map[string][]apisv1alpha1.AcceptablePermissionClaim, error) {
+	offered := map[string][]apisv1alpha1.PermissionClaim{}
+	for _, export := range exports.List() {
+		clusterName, name := logicalcluster.New(export).Split()
+		apiExport, err := kcpClient.Cluster(clusterName).ApisV1alpha1().APIExports().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get apiexport %s: %w", export, err)
+		}
+		offered[export] = apiExport.Spec.PermissionClaims
+	}
+	return acceptPermissionClaims(offered, o.AcceptPermissionClaims)
+}
+
+// acceptPermissionClaims returns the accepted claims out of the claims offered by each APIExport. A requested claim
+// has to be offered by at least one of the APIExports.
+func acceptPermissionClaims(offered map[string][]apisv1alpha1.PermissionClaim, requested []string) (map[string][]apisv1alpha1.AcceptablePermissionClaim, error) {
+	requestedClaims := sets.NewString(requested...)
+	all := requestedClaims.Has("all")
+
+	offeredClaims := sets.NewString()
+	accepted := map[string][]apisv1alpha1.AcceptablePermissionClaim{}
+	for export, claims := range offered {
+		for _, claim := range claims {
+			key := permissionClaimKey(claim)
+			offeredClaims.Insert(key)
+			if !all && !requestedClaims.Has(key) {
+				continue
+			}
+			accepted[export] = append(accepted[export], apisv1alpha1.AcceptablePermissionClaim{
+				PermissionClaim: claim,
+				State:           apisv1alpha1.ClaimAccepted,
+			})
+		}
+	}
+
+	if !all {
+		if missing := requestedClaims.Difference(offeredClaims); missing.Len() > 0 {
+			return nil, fmt.Errorf("the following permission claims are not offered by the APIExports: %s", strings.Join(missing.List(), ","))
+		}
+	}
+	return accepted, nil
+}
+
+// permissionClaimKey returns the <resource>.<group> form of the claim used by --accept-permission-claims, or just
+// <resource> for core resources.
+func permissionClaimKey(claim apisv1alpha1.PermissionClaim) string {
+	if len(claim.Group) == 0 {
+		return claim.Resource
+	}
+	return claim.Resource + "." + claim.Group
+}
+
+// placementSpec returns the spec of the placement to create from the completed options. Every field of
+// schedulingv1alpha1.PlacementSpec is set from a flag: --namespace-selector, --location-selectors, the location
+// workspace argument and --location-resource. PlacementSpec has no scheduling strategy or weight field yet; once it
+// does, it is to be set here from a flag, leaving it empty by default.
+func (o *BindComputeOptions) placementSpec() schedulingv1alpha1.PlacementSpec {
+	return schedulingv1alpha1.PlacementSpec{
+		NamespaceSelector: o.namespaceSelector,
+		LocationSelectors: o.locationSelectors,
+		LocationWorkspace: o.LocationWorkspace.String(),
+		LocationResource:  o.locationResource,
+	}
+}
+
+func (o *BindComputeOptions) applyPlacement(ctx context.Context, client kcpclient.Interface) (*schedulingv1alpha1.Placement, error) {
+	placement := &schedulingv1alpha1.Placement{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            o.PlacementName,
+			Annotations:     o.annotations,
+			OwnerReferences: o.ownerReferences,
+		},
+		Spec: o.placementSpec(),
+	}
+	key, err := idempotencyKey(placement)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.PrintPlacementSpec {
+		if err := o.printPlacementSpec(placement); err != nil {
+			return nil, err
+		}
+	}
+
+	if o.ShowCommands {
+		if err := o.printCommand(placement); err != nil {
+			return nil, err
+		}
+	}
+
+	if o.ServerSideApply {
+		data, err := applyPatch(placement)
+		if err != nil {
+			return nil, err
+		}
+		applied, err := client.SchedulingV1alpha1().Placements().Patch(ctx, placement.Name, types.ApplyPatchType, data, metav1.PatchOptions{FieldManager: o.FieldManager})
+		if err != nil {
+			return nil, err
+		}
+		_, err = fmt.Fprintf(o.Out, "placement %s applied.\n", applied.Name)
+		return applied, err
+	}
+
+	// the key is only set on create, server-side apply converges anyway.
+	placement.Annotations = map[string]string{IdempotencyKeyAnnotationKey: key}
+	for k, v := range o.annotations {
+		placement.Annotations[k] = v
+	}
+
+	action := "created"
+	created, err := client.SchedulingV1alpha1().Placements().Create(ctx, placement, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		created, err = client.SchedulingV1alpha1().Placements().Get(ctx, placement.Name, metav1.GetOptions{})
+		if err == nil && o.Replace && !apiequality.Semantic.DeepEqual(created.Spec, placement.Spec) {
+			action = "replaced"
+			created, err = o.replacePlacement(ctx, client, placement)
+		} else if err == nil {
+			switch existingKey, ok := created.Annotations[IdempotencyKeyAnnotationKey]; {
+			case !ok:
+				// not created by bind compute, so it cannot be told which inputs it was created from.
+				action = "already exists"
+			case existingKey == key:
+				// a previous attempt of the same create succeeded, even though it failed on our side.
+				action = "unchanged"
+			default:
+				return nil, apierrors.NewConflict(schedulingv1alpha1.Resource("placements"), placement.Name,
+					errors.New("it was created by another bind with different inputs, use --replace or another --name"))
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = fmt.Fprintf(o.Out, "placement %s %s.\n", created.Name, action)
+	return created, err
+}
@@ -0,0 +1,230 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/yaml"
+)
+
+// bindComputeBatch is the content of the batch file, e.g.:
+//
+//	binds:
+//	- targetWorkspace: root:org:team-a
+//	  locationWorkspace: root:mylocations
+//	  apiExports:
+//	  - root:compute:kubernetes
+//	  locationSelectors:
+//	  - env=prod
+//	- targetWorkspace: root:org:team-b
+//	  locationWorkspace: root:mylocations
+type bindComputeBatch struct {
+	Binds []bindComputeRequest `json:"binds"`
+}
+
+// bindComputeRequest is a single bind of the batch. The fields not given default to the flags.
+type bindComputeRequest struct {
+	TargetWorkspace   string   `json:"targetWorkspace,omitempty"`
+	LocationWorkspace string   `json:"locationWorkspace"`
+	APIExports        []string `json:"apiExports,omitempty"`
+	NamespaceSelector string   `json:"namespaceSelector,omitempty"`
+	LocationSelectors []string `json:"locationSelectors,omitempty"`
+	PlacementName     string   `json:"placementName,omitempty"`
+}
+
+// completeBatch loads the bind requests of the batch file, and completes the options of each of them.
+func (o *BindComputeOptions) completeBatch() error {
+	data, err := os.ReadFile(o.Batch)
+	if err != nil {
+		return fmt.Errorf("failed to read batch file: %w", err)
+	}
+	var batch bindComputeBatch
+	if err := yaml.UnmarshalStrict(data, &batch); err != nil {
+		return fmt.Errorf("failed to parse batch file %s: %w", o.Batch, err)
+	}
+	if len(batch.Binds) == 0 {
+		return fmt.Errorf("batch file %s has no bind requests", o.Batch)
+	}
+
+	var errs []error
+	for i, request := range batch.Binds {
+		if len(request.LocationWorkspace) == 0 {
+			errs = append(errs, fmt.Errorf("bind request %d: a location workspace should be specified", i+1))
+			continue
+		}
+		entry := o.batchEntry(request)
+		if err := entry.completeBind([]string{request.LocationWorkspace}); err != nil {
+			errs = append(errs, fmt.Errorf("bind request %d: %w", i+1, err))
+			continue
+		}
+		o.batch = append(o.batch, entry)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// batchEntry returns a copy of the options for the given bind request, with the fields it gives overridden. The copy
+// has its own base options, so that it can be given its own streams.
+func (o *BindComputeOptions) batchEntry(request bindComputeRequest) *BindComputeOptions {
+	entry := *o
+	options := *o.Options
+	entry.Options = &options
+	entry.Batch = ""
+	entry.batch = nil
+	entry.wildcard = false
+
+	if len(request.TargetWorkspace) > 0 {
+		entry.TargetWorkspace = request.TargetWorkspace
+	}
+	if len(request.APIExports) > 0 {
+		entry.APIExports = request.APIExports
+	}
+	if len(request.NamespaceSelector) > 0 {
+		entry.NamespaceSelectorString = request.NamespaceSelector
+	}
+	if len(request.LocationSelectors) > 0 {
+		entry.LocationSelectorsStrings = request.LocationSelectors
+	}
+	if len(request.PlacementName) > 0 {
+		entry.PlacementName = request.PlacementName
+	}
+	return &entry
+}
+
+// validateBatch validates the options shared by the bind requests of the batch, and the options of each of them.
+// With a wildcard location workspace, the shared options are validated the same way, and the binds to the workspaces
+// are validated once found in Run.
+func (o *BindComputeOptions) validateBatch() error {
+	var errs []error
+
+	source := "--batch"
+	if o.wildcard {
+		source = "a wildcard location workspace"
+		// the breadth of the bind is only known once the workspaces are found.
+		if !o.Yes {
+			errs = append(errs, errors.New("--yes is required with a wildcard location workspace, as it binds to every workspace found"))
+		}
+		if len(o.PlacementName) > 0 {
+			errs = append(errs, errors.New("--name cannot be used with a wildcard location workspace, each placement is named after its location workspace"))
+		}
+	}
+
+	if o.BatchConcurrency < 1 {
+		errs = append(errs, errors.New("--batch-concurrency must be at least 1"))
+	}
+
+	if o.Output == "name-vars" {
+		errs = append(errs, fmt.Errorf("-o name-vars cannot be used with %s", source))
+	}
+	if o.PrintRef {
+		errs = append(errs, fmt.Errorf("--print-ref cannot be used with %s", source))
+	}
+
+	// these are written by every bind request, which would overwrite each other.
+	if len(o.OutputFile) > 0 {
+		errs = append(errs, fmt.Errorf("--output-file cannot be used with %s", source))
+	}
+	if len(o.FailureReport) > 0 {
+		errs = append(errs, fmt.Errorf("--failure-report cannot be used with %s", source))
+	}
+	if len(o.DebugDumpOnFailure) > 0 {
+		errs = append(errs, fmt.Errorf("--debug-dump-on-failure cannot be used with %s", source))
+	}
+
+	// bind requests run concurrently with their output buffered, so they cannot prompt.
+	if o.labelsNamespaces() && !o.Yes && !o.wildcard {
+		errs = append(errs, errors.New("--yes is required to label namespaces with --batch"))
+	}
+	if o.Replace && !o.Yes && !o.wildcard {
+		errs = append(errs, errors.New("--yes is required with --replace and --batch"))
+	}
+
+	for i, entry := range o.batch {
+		if err := entry.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("bind request %d: %w", i+1, err))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// runBatch runs the bind requests of the batch, up to BatchConcurrency at a time, and prints a report of the result
+// of each of them. The output of each bind request is printed at once when it is done, so that concurrent bind
+// requests do not interleave.
+func (o *BindComputeOptions) runBatch(ctx context.Context) error {
+	results := make([]error, len(o.batch))
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, o.BatchConcurrency)
+	for i, entry := range o.batch {
+		i, entry := i, entry
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			var out, errOut bytes.Buffer
+			entry.IOStreams = genericclioptions.IOStreams{In: o.In, Out: &out, ErrOut: &errOut}
+			entry.deferReport = o.deferReport
+			results[i] = entry.Run(ctx)
+
+			lock.Lock()
+			defer lock.Unlock()
+			if _, err := o.ErrOut.Write(errOut.Bytes()); err != nil && results[i] == nil {
+				results[i] = err
+			}
+			if _, err := o.Out.Write(out.Bytes()); err != nil && results[i] == nil {
+				results[i] = err
+			}
+		}()
+	}
+	wg.Wait()
+
+	failed := 0
+	for i, entry := range o.batch {
+		result := "succeeded"
+		if results[i] != nil {
+			result = fmt.Sprintf("failed: %v", results[i])
+			failed++
+		}
+		if _, err := fmt.Fprintf(o.Out, "bind request %d (%s to location workspace %s): %s\n", i+1, entry.targetDescription(), entry.LocationWorkspace, result); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d bind request(s) failed", failed, len(o.batch))
+	}
+	return nil
+}
+
+// targetDescription describes the workspace the APIBindings and placement are created in.
+func (o *BindComputeOptions) targetDescription() string {
+	if o.targetWorkspace.Empty() {
+		return "current workspace"
+	}
+	return "workspace " + o.targetWorkspace.String()
+}
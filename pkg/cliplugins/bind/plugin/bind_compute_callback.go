@@ -0,0 +1,85 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+// callbackTimeout is the time the callback URL is given to respond.
+const callbackTimeout = time.Second * 10
+
+// callbackPayload is the JSON payload posted to --callback-url when the bind is done.
+type callbackPayload struct {
+	// Status is either succeeded or failed.
+	Status            string             `json:"status"`
+	Error             string             `json:"error,omitempty"`
+	LocationWorkspace string             `json:"locationWorkspace"`
+	Placements        []string           `json:"placements"`
+	APIBindings       []apiBindingReport `json:"apiBindings"`
+}
+
+// newCallbackPayload returns the payload of a bind done with the given error, nil if it succeeded.
+func (o *BindComputeOptions) newCallbackPayload(runErr error, bindings []*apisv1alpha1.APIBinding) *callbackPayload {
+	payload := &callbackPayload{
+		Status:            "succeeded",
+		LocationWorkspace: o.LocationWorkspace.String(),
+		APIBindings:       newAPIBindingReports(bindings),
+	}
+	if runErr != nil {
+		payload.Status = "failed"
+		payload.Error = runErr.Error()
+	}
+	for _, po := range o.placementOptions() {
+		payload.Placements = append(payload.Placements, po.PlacementName)
+	}
+	return payload
+}
+
+// postCallback posts the result of the bind to --callback-url.
+func (o *BindComputeOptions) postCallback(runErr error, bindings []*apisv1alpha1.APIBinding) error {
+	data, err := json.Marshal(o.newCallbackPayload(runErr, bindings))
+	if err != nil {
+		return fmt.Errorf("failed to serialize callback payload: %w", err)
+	}
+
+	// the bind context might be done already, e.g. after --deadline, while the failure should still be posted.
+	ctx, cancel := context.WithTimeout(context.Background(), callbackTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.CallbackURL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to post to callback URL %s: %w", o.CallbackURL, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to callback URL %s: %w", o.CallbackURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("callback URL %s responded with %s", o.CallbackURL, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"k8s.io/component-base/version"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	"github.com/kcp-dev/kcp/pkg/cliplugins/base"
+	"github.com/kcp-dev/kcp/pkg/cliplugins/helpers"
+)
+
+// currentWorkspace returns the workspace the kubeconfig points to.
+func (o *BindComputeOptions) currentWorkspace() (logicalcluster.Name, error) {
+	config, err := o.ClientConfig.ClientConfig()
+	if err != nil {
+		return logicalcluster.Name{}, err
+	}
+	_, currentClusterName, err := helpers.ParseClusterURL(config.Host)
+	if err != nil {
+		return logicalcluster.Name{}, fmt.Errorf("current URL %q does not point to cluster workspace", config.Host)
+	}
+	return currentClusterName, nil
+}
+
+// newClients returns a client for the current workspace and a cluster client to reach the location workspace.
+func (o *BindComputeOptions) newClients() (kcpclient.Interface, kcpclient.ClusterInterface, error) {
+	config, kcpConfig, err := o.clientConfigs()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userWorkspaceKcpClient, kcpClient, err := base.NewKcpClients(config, kcpConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !o.targetWorkspace.Empty() {
+		return kcpClient.Cluster(o.targetWorkspace), kcpClient, nil
+	}
+
+	return userWorkspaceKcpClient, kcpClient, nil
+}
+
+// clientConfigs returns the config of the current workspace and the config of the cluster client, with the client
+// flags applied. With --direct-url, the cluster config points to it rather than to the server of the current workspace.
+func (o *BindComputeOptions) clientConfigs() (*rest.Config, *rest.Config, error) {
+	var config, kcpConfig *rest.Config
+	var err error
+	if len(o.DirectURL) > 0 {
+		if config, err = o.ClientConfig.ClientConfig(); err != nil {
+			return nil, nil, err
+		}
+		kcpConfig = rest.CopyConfig(config)
+		kcpConfig.Host = strings.TrimSuffix(o.DirectURL, "/")
+	} else if config, kcpConfig, err = o.ClientConfigs(); err != nil {
+		// kcpConfig connects to the location workspace
+		return nil, nil, err
+	}
+	config = rest.CopyConfig(config)
+	for _, c := range []*rest.Config{config, kcpConfig} {
+		c.UserAgent = o.UserAgent
+		if len(c.UserAgent) == 0 {
+			c.UserAgent = "kcp-bind-compute/" + version.Get().GitVersion
+		}
+		c.QPS = o.QPS
+		c.Burst = o.Burst
+	}
+	o.applyTLSOverrides(kcpConfig)
+
+	if o.Trace {
+		// client-go wraps the transport of the config under its authentication round trippers, so the tracer sees
+		// the Authorization header they set.
+		t := &tracer{out: o.ErrOut}
+		config.Wrap(t.wrap)
+		kcpConfig.Wrap(t.wrap)
+	}
+
+	return config, kcpConfig, nil
+}
+
+// tokenOnly returns whether the connection is entirely given by --server and --token, without --kubeconfig. The
+// --certificate-authority and --insecure-skip-tls-verify flags apply as usual.
+func (o *BindComputeOptions) tokenOnly() bool {
+	return len(o.Kubeconfig) == 0 && len(o.KubectlOverrides.ClusterInfo.Server) > 0 && len(o.KubectlOverrides.AuthInfo.Token) > 0
+}
+
+// workspaceURLs returns the URLs the current workspace, or --target-workspace, and the location workspace are
+// reached at.
+func (o *BindComputeOptions) workspaceURLs() (string, string, error) {
+	config, kcpConfig, err := o.clientConfigs()
+	if err != nil {
+		return "", "", err
+	}
+	userWorkspaceURL := config.Host
+	if !o.targetWorkspace.Empty() {
+		userWorkspaceURL = kcpConfig.Host + o.targetWorkspace.Path()
+	}
+	return userWorkspaceURL, kcpConfig.Host + o.LocationWorkspace.Path(), nil
+}
+
+// checkReachable checks that the workspace of the client can be reached with a lightweight list call. Any response
+// from the server, even a forbidden one, tells the workspace is reachable.
+func checkReachable(ctx context.Context, client kcpclient.Interface, description, url string) error {
+	_, err := client.ApisV1alpha1().APIBindings().List(ctx, metav1.ListOptions{Limit: 1})
+	var status apierrors.APIStatus
+	if err == nil || errors.As(err, &status) {
+		return nil
+	}
+	return fmt.Errorf("cannot reach %s at %s: %w", description, url, err)
+}
+
+// requiredAPI is an API group version bind compute uses, and the resources it uses it for.
+type requiredAPI struct {
+	groupVersion schema.GroupVersion
+	resources    string
+}
+
+var (
+	// userWorkspaceAPIs are the APIs used in the workspace the APIBindings and placement are created in.
+	userWorkspaceAPIs = []requiredAPI{
+		{groupVersion: apisv1alpha1.SchemeGroupVersion, resources: "APIBindings"},
+		{groupVersion: schedulingv1alpha1.SchemeGroupVersion, resources: "Placements"},
+	}
+
+	// locationWorkspaceAPIs are the APIs used in the location workspace.
+	locationWorkspaceAPIs = []requiredAPI{
+		{groupVersion: workloadv1alpha1.SchemeGroupVersion, resources: "SyncTargets"},
+		{groupVersion: schedulingv1alpha1.SchemeGroupVersion, resources: "Locations"},
+	}
+)
+
+// checkAPIGroups checks with discovery that the server serves the given APIs, to report a kcp version mismatch
+// clearly rather than with the no-match error of the first request using them. Discovery errors are ignored, the
+// requests fail on their own then.
+func checkAPIGroups(client discovery.DiscoveryInterface, apis []requiredAPI) error {
+	groups, err := client.ServerGroups()
+	if err != nil {
+		return nil
+	}
+	served := sets.NewString()
+	for _, group := range groups.Groups {
+		for _, version := range group.Versions {
+			served.Insert(version.GroupVersion)
+		}
+	}
+	for _, api := range apis {
+		if !served.Has(api.groupVersion.String()) {
+			return fmt.Errorf("this kcp server does not support %s (%s); upgrade kcp or the plugin", api.resources, api.groupVersion)
+		}
+	}
+	return nil
+}
+
+// checkLocationResource checks with discovery that the location resource is served in the location workspace, by its
+// plural name. Discovery errors other than a missing group version are ignored, creating the placement fails on its
+// own then.
+func (o *BindComputeOptions) checkLocationResource(client discovery.DiscoveryInterface) error {
+	groupVersion := schema.GroupVersion{Group: o.locationResource.Group, Version: o.locationResource.Version}
+	resources, err := client.ServerResourcesForGroupVersion(groupVersion.String())
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("location resource %s is not served in workspace %s: group version %s not found", formatLocationResource(o.locationResource), o.LocationWorkspace, groupVersion)
+	}
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, resource := range resources.APIResources {
+		if resource.Name == o.locationResource.Resource {
+			return nil
+		}
+		if resource.SingularName == o.locationResource.Resource || strings.ToLower(resource.Kind) == o.locationResource.Resource {
+			return fmt.Errorf("location resource %s is the singular name of %s, use the plural resource name %s instead",
+				formatLocationResource(o.locationResource), resource.Name, resource.Name)
+		}
+		if !strings.Contains(resource.Name, "/") {
+			names = append(names, resource.Name)
+		}
+	}
+	sort.Strings(names)
+	return fmt.Errorf("location resource %s is not served in workspace %s, %s serves: %s", formatLocationResource(o.locationResource), o.LocationWorkspace, groupVersion, strings.Join(names, ", "))
+}
+
+// checkProtectedWorkspace refuses to bind into one of --protected-workspaces, unless --allow-root is set. A current
+// workspace that cannot be told from the server URL, e.g. with --direct-url, is not checked.
+func (o *BindComputeOptions) checkProtectedWorkspace() error {
+	if o.AllowRoot {
+		return nil
+	}
+
+	workspace := o.targetWorkspace
+	if workspace.Empty() {
+		config, err := o.ClientConfig.ClientConfig()
+		if err != nil {
+			return err
+		}
+		if _, workspace, err = helpers.ParseClusterURL(config.Host); err != nil {
+			return nil
+		}
+	}
+
+	if sets.NewString(o.ProtectedWorkspaces...).Has(workspace.String()) {
+		return fmt.Errorf("refusing to create APIBindings and placement in the protected workspace %s, as it is shared; "+
+			"switch to another workspace, or pass --allow-root if this is really intended", workspace)
+	}
+	return nil
+}
+
+// checkTargetWorkspace verifies the workspace given with --target-workspace exists and can be reached.
+func (o *BindComputeOptions) checkTargetWorkspace(ctx context.Context, kcpClient kcpclient.ClusterInterface) error {
+	parentClusterName, workspaceName := o.targetWorkspace.Split()
+	if _, err := kcpClient.Cluster(parentClusterName).TenancyV1beta1().Workspaces().Get(ctx, workspaceName, metav1.GetOptions{}); apierrors.IsNotFound(err) {
+		return fmt.Errorf("target workspace %s not found", o.targetWorkspace)
+	}
+
+	// a 403 on the parent is not a blocker, check the workspace itself can be reached.
+	if _, err := kcpClient.Cluster(o.targetWorkspace).Discovery().ServerGroups(); err != nil && !apierrors.IsForbidden(err) {
+		return fmt.Errorf("target workspace %s cannot be reached: %w", o.targetWorkspace, err)
+	}
+	return nil
+}
+
+// applyTLSOverrides applies the --insecure-skip-tls-verify and --certificate-authority flags to the given config,
+// replacing the TLS settings copied over from the kubeconfig, which might not apply to a rewritten host.
+func (o *BindComputeOptions) applyTLSOverrides(config *rest.Config) {
+	clusterInfo := o.KubectlOverrides.ClusterInfo
+	if len(clusterInfo.CertificateAuthority) > 0 {
+		config.TLSClientConfig.CAFile = clusterInfo.CertificateAuthority
+		config.TLSClientConfig.CAData = nil
+	}
+	if clusterInfo.InsecureSkipTLSVerify {
+		config.TLSClientConfig.Insecure = true
+		config.TLSClientConfig.CAFile = ""
+		config.TLSClientConfig.CAData = nil
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	kcpscheme "github.com/kcp-dev/kcp/pkg/client/clientset/versioned/scheme"
+)
+
+// debugDumpTimeout is the time given to fetch the objects of the debug dump, after the bind failed.
+const debugDumpTimeout = time.Second * 30
+
+// sensitiveAnnotations are substrings of the annotation keys whose values are redacted in the debug dump, as they
+// might hold credentials.
+var sensitiveAnnotations = []string{"token", "secret", "password", "credential", "last-applied-configuration"}
+
+// writeDebugDump writes the YAML of the placements, the APIBindings of the requested and supported APIExports, and
+// the SyncTargets and Locations of the location workspace to --debug-dump-on-failure, status included. Objects that
+// cannot be fetched are noted in comments, so that the dump has as much as can be read.
+func (o *BindComputeOptions) writeDebugDump(supportedExports sets.String) error {
+	userWorkspaceKcpClient, kcpClient, err := o.newClients()
+	if err != nil {
+		return fmt.Errorf("failed to write debug dump: %w", err)
+	}
+
+	// the bind context might be done already, e.g. after --deadline, while the objects should still be dumped.
+	ctx, cancel := context.WithTimeout(context.Background(), debugDumpTimeout)
+	defer cancel()
+
+	var buf bytes.Buffer
+	o.dumpDebugObjects(ctx, &buf, userWorkspaceKcpClient, kcpClient.Cluster(o.LocationWorkspace), supportedExports)
+
+	if err := os.MkdirAll(filepath.Dir(o.DebugDumpOnFailure), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", o.DebugDumpOnFailure, err)
+	}
+	// the dump is meant to be shared, but might still reveal more about the workspaces than the user intends to.
+	if err := os.WriteFile(o.DebugDumpOnFailure, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", o.DebugDumpOnFailure, err)
+	}
+	_, err = fmt.Fprintf(o.ErrOut, "wrote debug dump to %s.\n", o.DebugDumpOnFailure)
+	return err
+}
+
+// dumpDebugObjects writes the objects of the debug dump to buf.
+func (o *BindComputeOptions) dumpDebugObjects(ctx context.Context, buf *bytes.Buffer, client, locationClient kcpclient.Interface, supportedExports sets.String) {
+	dump := func(description string, obj runtime.Object) {
+		manifest, err := debugObjectYAML(obj)
+		if err != nil {
+			fmt.Fprintf(buf, "---\n# failed to serialize %s: %v\n", description, err)
+			return
+		}
+		buf.WriteString("---\n")
+		buf.Write(manifest)
+	}
+
+	for _, po := range o.placementOptions() {
+		placement, err := client.SchedulingV1alpha1().Placements().Get(ctx, po.PlacementName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			fmt.Fprintf(buf, "---\n# placement %s does not exist\n", po.PlacementName)
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(buf, "---\n# failed to get placement %s: %v\n", po.PlacementName, err)
+			continue
+		}
+		dump("placement "+placement.Name, placement)
+	}
+
+	exports := sets.NewString(o.APIExports...).Union(supportedExports)
+	if apiBindings, err := listAPIBindings(ctx, client); err != nil {
+		fmt.Fprintf(buf, "---\n# failed to list apibindings: %v\n", err)
+	} else {
+		for i := range apiBindings {
+			binding := &apiBindings[i]
+			if binding.Spec.Reference.Workspace != nil && exports.Has(exportReferenceKey(binding.Spec.Reference.Workspace)) {
+				dump("apibinding "+binding.Name, binding)
+			}
+		}
+	}
+
+	if syncTargets, err := listSyncTargets(ctx, locationClient, ""); err != nil {
+		fmt.Fprintf(buf, "---\n# failed to list synctargets in workspace %s: %v\n", o.LocationWorkspace, err)
+	} else {
+		for i := range syncTargets {
+			dump("synctarget "+syncTargets[i].Name, &syncTargets[i])
+		}
+	}
+
+	if locations, err := locationClient.SchedulingV1alpha1().Locations().List(ctx, metav1.ListOptions{}); err != nil {
+		fmt.Fprintf(buf, "---\n# failed to list locations in workspace %s: %v\n", o.LocationWorkspace, err)
+	} else {
+		for i := range locations.Items {
+			dump("location "+locations.Items[i].Name, &locations.Items[i])
+		}
+	}
+}
+
+// debugObjectYAML returns the YAML of the given object with its apiVersion, kind and status, without managed fields,
+// and with the values of sensitiveAnnotations redacted.
+func debugObjectYAML(obj runtime.Object) ([]byte, error) {
+	gvks, _, err := kcpscheme.Scheme.ObjectKinds(obj)
+	if err != nil {
+		return nil, err
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(gvks[0])
+	unstructured.RemoveNestedField(u.Object, "metadata", "managedFields")
+
+	if annotations := u.GetAnnotations(); annotations != nil {
+		for key := range annotations {
+			for _, sensitive := range sensitiveAnnotations {
+				if strings.Contains(strings.ToLower(key), sensitive) {
+					annotations[key] = "<redacted>"
+				}
+			}
+		}
+		u.SetAnnotations(annotations)
+	}
+
+	return yaml.Marshal(u.Object)
+}
@@ -0,0 +1,282 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/spf13/cobra"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	"github.com/kcp-dev/kcp/pkg/cliplugins/base"
+	"github.com/kcp-dev/kcp/pkg/cliplugins/helpers"
+)
+
+// BindComputeDeleteOptions contains the options for deleting placements created by bind compute.
+type BindComputeDeleteOptions struct {
+	*base.Options
+
+	// PlacementNames are the names of the placements to delete.
+	PlacementNames []string
+
+	// All deletes all placements in the workspace.
+	All bool
+
+	// LocationWorkspace restricts the deleted placements to those linked to this location workspace.
+	LocationWorkspace string
+
+	// Yes skips the confirmation prompt.
+	Yes bool
+
+	// ForceDelete deletes without waiting for dependents, and waits up to Timeout for the placements to be gone,
+	// reporting the finalizers of those still blocked.
+	ForceDelete bool
+
+	// Timeout is how long ForceDelete waits for the placements to be gone.
+	Timeout time.Duration
+
+	// PollInterval is the interval between checks that the placements are gone.
+	PollInterval time.Duration
+}
+
+// NewBindComputeDeleteOptions returns new BindComputeDeleteOptions.
+func NewBindComputeDeleteOptions(streams genericclioptions.IOStreams) *BindComputeDeleteOptions {
+	return &BindComputeDeleteOptions{
+		Options:      base.NewOptions(streams),
+		Timeout:      time.Second * 30,
+		PollInterval: time.Millisecond * 500,
+	}
+}
+
+// BindFlags binds fields to cmd's flagset.
+func (o *BindComputeDeleteOptions) BindFlags(cmd *cobra.Command) {
+	o.Options.BindFlags(cmd)
+
+	cmd.Flags().BoolVar(&o.All, "all", o.All, "Delete all placements in the workspace.")
+	cmd.Flags().StringVar(&o.LocationWorkspace, "location-workspace", o.LocationWorkspace, "Only delete placements linked to this location workspace.")
+	cmd.Flags().BoolVarP(&o.Yes, "yes", "y", o.Yes, "Delete without asking for confirmation.")
+	cmd.Flags().BoolVar(&o.ForceDelete, "force-delete", o.ForceDelete, "Delete in the background without waiting for dependents, then wait up to --timeout for the placements to be gone, "+
+		"reporting the finalizers blocking those that are not, e.g. when the scheduling controller is down.")
+	cmd.Flags().DurationVar(&o.Timeout, "timeout", o.Timeout, "With --force-delete, how long to wait for the placements to be gone.")
+}
+
+// Complete ensures all fields are initialized.
+func (o *BindComputeDeleteOptions) Complete(args []string) error {
+	if err := o.Options.Complete(); err != nil {
+		return err
+	}
+
+	o.PlacementNames = args
+	return nil
+}
+
+// Validate validates the BindComputeDeleteOptions are complete and usable.
+func (o *BindComputeDeleteOptions) Validate() error {
+	var errs []error
+
+	if err := o.Options.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if o.All && len(o.PlacementNames) > 0 {
+		errs = append(errs, errors.New("placement names cannot be specified together with --all"))
+	}
+
+	if !o.All && len(o.PlacementNames) == 0 {
+		errs = append(errs, errors.New("either placement names or --all is required"))
+	}
+
+	if o.Timeout <= 0 {
+		errs = append(errs, errors.New("--timeout must be positive"))
+	}
+
+	if o.Kubeconfig == "-" && !o.Yes {
+		errs = append(errs, errors.New("--yes is required when reading the kubeconfig from stdin"))
+	}
+
+	if o.LocationWorkspace != "" && !logicalcluster.New(o.LocationWorkspace).IsValid() {
+		errs = append(errs, fmt.Errorf("location workspace %q is not a valid workspace path", o.LocationWorkspace))
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// Run deletes the selected placements in the current workspace.
+func (o *BindComputeDeleteOptions) Run(ctx context.Context) error {
+	config, err := o.ClientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	_, currentClusterName, err := helpers.ParseClusterURL(config.Host)
+	if err != nil {
+		return fmt.Errorf("current URL %q does not point to cluster workspace", config.Host)
+	}
+	client, _, err := o.KcpClients()
+	if err != nil {
+		return err
+	}
+
+	placements, err := o.placementsToDelete(ctx, client)
+	if err != nil {
+		return err
+	}
+	if len(placements) == 0 {
+		_, err := fmt.Fprintf(o.Out, "no placements to delete in workspace %s.\n", currentClusterName)
+		return err
+	}
+
+	if !o.Yes {
+		confirmed, err := confirm(o.IOStreams, fmt.Sprintf("Delete %d placement(s) in workspace %s?", len(placements), currentClusterName))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			_, err := fmt.Fprintln(o.Out, "aborted.")
+			return err
+		}
+	}
+
+	var deleteOptions metav1.DeleteOptions
+	if o.ForceDelete {
+		propagation := metav1.DeletePropagationBackground
+		deleteOptions.PropagationPolicy = &propagation
+	}
+
+	var errs []error
+	var deletedNames []string
+	deleted := 0
+	for _, placement := range placements {
+		if err := client.SchedulingV1alpha1().Placements().Delete(ctx, placement.Name, deleteOptions); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, err)
+			continue
+		}
+		deleted++
+		deletedNames = append(deletedNames, placement.Name)
+
+		if _, err := fmt.Fprintf(o.Out, "placement %s deleted.\n", placement.Name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if _, err := fmt.Fprintf(o.Out, "deleted %d placement(s) in workspace %s.\n", deleted, currentClusterName); err != nil {
+		errs = append(errs, err)
+	}
+
+	if o.ForceDelete && len(deletedNames) > 0 {
+		if err := o.waitForDeletion(ctx, client, deletedNames); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// waitForDeletion waits up to --timeout for the named placements to be gone. The finalizers of the placements still
+// there are reported, rather than waiting for them indefinitely.
+func (o *BindComputeDeleteOptions) waitForDeletion(ctx context.Context, client kcpclient.Interface, names []string) error {
+	blocked := map[string][]string{}
+	err := wait.PollImmediateWithContext(ctx, o.PollInterval, o.Timeout, func(ctx context.Context) (bool, error) {
+		blocked = map[string][]string{}
+		for _, name := range names {
+			placement, err := client.SchedulingV1alpha1().Placements().Get(ctx, name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return false, err
+			}
+			blocked[name] = placement.Finalizers
+		}
+		return len(blocked) == 0, nil
+	})
+	if !errors.Is(err, wait.ErrWaitTimeout) {
+		return err
+	}
+
+	var errs []error
+	for _, name := range sets.StringKeySet(blocked).List() {
+		if len(blocked[name]) == 0 {
+			errs = append(errs, fmt.Errorf("placement %s is still being deleted after %s", name, o.Timeout))
+			continue
+		}
+		errs = append(errs, fmt.Errorf("placement %s is still being deleted after %s, blocked by finalizers: %s. "+
+			"Check the controllers responsible for them, or remove them by editing the placement", name, o.Timeout, strings.Join(blocked[name], ",")))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// placementsToDelete returns the placements selected by the options.
+func (o *BindComputeDeleteOptions) placementsToDelete(ctx context.Context, client kcpclient.Interface) ([]schedulingv1alpha1.Placement, error) {
+	var placements []schedulingv1alpha1.Placement
+	if o.All {
+		list, err := client.SchedulingV1alpha1().Placements().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		placements = list.Items
+	} else {
+		for _, name := range o.PlacementNames {
+			placement, err := client.SchedulingV1alpha1().Placements().Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			placements = append(placements, *placement)
+		}
+	}
+
+	if o.LocationWorkspace == "" {
+		return placements, nil
+	}
+
+	var filtered []schedulingv1alpha1.Placement
+	for _, placement := range placements {
+		if placement.Spec.LocationWorkspace == o.LocationWorkspace {
+			filtered = append(filtered, placement)
+		}
+	}
+	return filtered, nil
+}
+
+// confirm asks the user the given question on the input stream, and returns whether the answer was yes.
+func confirm(streams genericclioptions.IOStreams, question string) (bool, error) {
+	if _, err := fmt.Fprintf(streams.Out, "%s [y/N]: ", question); err != nil {
+		return false, err
+	}
+
+	answer, err := bufio.NewReader(streams.In).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+)
+
+// discoverLocationWorkspace sets the location workspace to the workspace around the current one whose SyncTargets
+// support the requested APIExports, within --timeout, and names the placements after it.
+func (o *BindComputeOptions) discoverLocationWorkspace(ctx context.Context, kcpClient kcpclient.ClusterInterface) error {
+	currentWorkspace, err := o.currentWorkspace()
+	if err != nil {
+		return err
+	}
+
+	if o.BindWaitTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.BindWaitTimeout)
+		defer cancel()
+	}
+	locationWorkspace, err := o.findLocationWorkspace(ctx, kcpClient, currentWorkspace)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("discovering the location workspace did not complete within --timeout %s: %w", o.BindWaitTimeout, err)
+	}
+	if err != nil {
+		return err
+	}
+	o.LocationWorkspace = locationWorkspace
+
+	// the default placement names depend on the location workspace.
+	if errs := o.completeSelectors(); len(errs) > 0 {
+		return utilerrors.NewAggregate(errs)
+	}
+	o.defaultPlacementNames()
+
+	_, err = fmt.Fprintf(o.ErrOut, "discovered location workspace %s.\n", locationWorkspace)
+	return err
+}
+
+// findLocationWorkspace returns the only workspace among the current workspace, its parent and their children whose
+// SyncTargets support the requested APIExports, or the default kubernetes APIExport without --apiexports. Workspaces
+// that cannot be listed or read are skipped. If several workspaces qualify, the user is asked to choose one on an
+// interactive terminal, unless --yes is given.
+func (o *BindComputeOptions) findLocationWorkspace(ctx context.Context, kcpClient kcpclient.ClusterInterface, currentWorkspace logicalcluster.Name) (logicalcluster.Name, error) {
+	parents := []logicalcluster.Name{currentWorkspace}
+	if parent, ok := currentWorkspace.Parent(); ok {
+		parents = append(parents, parent)
+	}
+
+	candidates := sets.NewString()
+	for _, parent := range parents {
+		if err := ctx.Err(); err != nil {
+			return logicalcluster.Name{}, err
+		}
+		candidates.Insert(parent.String())
+		workspaces, err := kcpClient.Cluster(parent).TenancyV1beta1().Workspaces().List(ctx, metav1.ListOptions{})
+		if apierrors.IsForbidden(err) {
+			continue
+		}
+		if err != nil {
+			return logicalcluster.Name{}, fmt.Errorf("failed to list workspaces in %s: %w", parent, err)
+		}
+		for _, workspace := range workspaces.Items {
+			candidates.Insert(parent.Join(workspace.Name).String())
+		}
+	}
+
+	var matches []string
+	for _, candidate := range candidates.List() {
+		if err := ctx.Err(); err != nil {
+			return logicalcluster.Name{}, err
+		}
+		if o.isLocationWorkspace(ctx, kcpClient, logicalcluster.New(candidate)) {
+			matches = append(matches, candidate)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return logicalcluster.Name{}, fmt.Errorf("no workspace around %s has synctargets supporting the APIExports, specify the location workspace", currentWorkspace)
+	case 1:
+		return logicalcluster.New(matches[0]), nil
+	default:
+		if !o.Yes && isTerminalInput(o.In) {
+			return o.chooseLocationWorkspace(matches)
+		}
+		return logicalcluster.Name{}, fmt.Errorf("several workspaces have synctargets supporting the APIExports, specify one of them as the location workspace: %s", strings.Join(matches, ", "))
+	}
+}
+
+// chooseLocationWorkspace asks which of the given workspaces to use as the location workspace.
+func (o *BindComputeOptions) chooseLocationWorkspace(matches []string) (logicalcluster.Name, error) {
+	if _, err := fmt.Fprintln(o.Out, "several workspaces have synctargets supporting the APIExports:"); err != nil {
+		return logicalcluster.Name{}, err
+	}
+	for i, match := range matches {
+		if _, err := fmt.Fprintf(o.Out, "  %d) %s\n", i+1, match); err != nil {
+			return logicalcluster.Name{}, err
+		}
+	}
+	if _, err := fmt.Fprintf(o.Out, "location workspace [1-%d]: ", len(matches)); err != nil {
+		return logicalcluster.Name{}, err
+	}
+
+	answer, err := bufio.NewReader(o.In).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return logicalcluster.Name{}, err
+	}
+	answer = strings.TrimSpace(answer)
+	choice, err := strconv.Atoi(answer)
+	if err != nil || choice < 1 || choice > len(matches) {
+		return logicalcluster.Name{}, fmt.Errorf("invalid choice %q, specify one of them as the location workspace: %s", answer, strings.Join(matches, ", "))
+	}
+	return logicalcluster.New(matches[choice-1]), nil
+}
+
+// isLocationWorkspace returns whether the SyncTargets of the workspace support the requested APIExports, or the
+// default kubernetes APIExport without --apiexports. Workspaces whose SyncTargets cannot be listed are not.
+func (o *BindComputeOptions) isLocationWorkspace(ctx context.Context, kcpClient kcpclient.ClusterInterface, workspace logicalcluster.Name) bool {
+	syncTargets, err := listSyncTargets(ctx, kcpClient.Cluster(workspace), o.SyncTargetSelector)
+	if err != nil || len(syncTargets) == 0 {
+		return false
+	}
+	return o.supportsRequestedAPIExports(workspace, syncTargetsAPIExports(syncTargets, workspace))
+}
+
+// bindWildcard discovers every accessible workspace whose SyncTargets support the requested APIExports, within
+// --timeout, and completes and validates the options of a bind to each of them, run like the bind requests of a batch.
+func (o *BindComputeOptions) bindWildcard(ctx context.Context, kcpClient kcpclient.ClusterInterface) error {
+	if o.BindWaitTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.BindWaitTimeout)
+		defer cancel()
+	}
+	locationWorkspaces, err := o.findAllLocationWorkspaces(ctx, kcpClient)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("discovering the location workspaces did not complete within --timeout %s: %w", o.BindWaitTimeout, err)
+	}
+	if err != nil {
+		return err
+	}
+	if len(locationWorkspaces) == 0 {
+		return fmt.Errorf("no accessible workspace has synctargets supporting the APIExports")
+	}
+	if _, err := fmt.Fprintf(o.ErrOut, "discovered %d location workspace(s): %s.\n", len(locationWorkspaces), strings.Join(locationWorkspaces, ", ")); err != nil {
+		return err
+	}
+
+	// Run may be attempted again, the workspaces found are those of the last attempt.
+	o.batch = nil
+	var errs []error
+	for _, locationWorkspace := range locationWorkspaces {
+		entry := o.batchEntry(bindComputeRequest{LocationWorkspace: locationWorkspace})
+		if err := entry.completeBind([]string{locationWorkspace}); err != nil {
+			errs = append(errs, fmt.Errorf("location workspace %s: %w", locationWorkspace, err))
+			continue
+		}
+		if err := entry.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("location workspace %s: %w", locationWorkspace, err))
+			continue
+		}
+		o.batch = append(o.batch, entry)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// findAllLocationWorkspaces walks the workspace tree from root, and returns all workspaces whose SyncTargets support
+// the requested APIExports, or the default kubernetes APIExport without --apiexports. Workspaces whose children
+// cannot be listed are not walked further.
+func (o *BindComputeOptions) findAllLocationWorkspaces(ctx context.Context, kcpClient kcpclient.ClusterInterface) ([]string, error) {
+	var matches []string
+	queue := []logicalcluster.Name{tenancyv1alpha1.RootCluster}
+	for len(queue) > 0 {
+		workspace := queue[0]
+		queue = queue[1:]
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if o.isLocationWorkspace(ctx, kcpClient, workspace) {
+			matches = append(matches, workspace.String())
+		}
+
+		workspaces, err := kcpClient.Cluster(workspace).TenancyV1beta1().Workspaces().List(ctx, metav1.ListOptions{})
+		if apierrors.IsForbidden(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list workspaces in %s: %w", workspace, err)
+		}
+		for _, child := range workspaces.Items {
+			queue = append(queue, workspace.Join(child.Name))
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// supportsRequestedAPIExports returns whether the APIExports supported by the SyncTargets of the location workspace
+// include all requested APIExports, or one of the default kubernetes APIExports without --apiexports.
+func (o *BindComputeOptions) supportsRequestedAPIExports(locationWorkspace logicalcluster.Name, supportedExports sets.String) bool {
+	if len(o.APIExports) > 0 {
+		return supportedExports.HasAll(o.APIExports...)
+	}
+	return supportedExports.HasAny(o.defaultAPIExports(locationWorkspace)...)
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/spf13/cobra"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	"github.com/kcp-dev/kcp/pkg/cliplugins/base"
+)
+
+// BindComputeExplainOptions contains the options for explaining what an APIExport offers to bind compute.
+type BindComputeExplainOptions struct {
+	*base.Options
+
+	// APIExport is the APIExport to explain, as <absolute_ref_to_workspace>:<apiexport>.
+	APIExport string
+
+	// LocationWorkspace is the workspace whose SyncTargets supporting the APIExport are listed.
+	LocationWorkspace string
+}
+
+// NewBindComputeExplainOptions returns new BindComputeExplainOptions.
+func NewBindComputeExplainOptions(streams genericclioptions.IOStreams) *BindComputeExplainOptions {
+	return &BindComputeExplainOptions{
+		Options: base.NewOptions(streams),
+	}
+}
+
+// BindFlags binds fields to cmd's flagset.
+func (o *BindComputeExplainOptions) BindFlags(cmd *cobra.Command) {
+	o.Options.BindFlags(cmd)
+
+	cmd.Flags().StringVar(&o.LocationWorkspace, "location-workspace", o.LocationWorkspace, "Absolute path of the location workspace to list the synctargets supporting the APIExport in.")
+}
+
+// Complete ensures all fields are initialized.
+func (o *BindComputeExplainOptions) Complete(args []string) error {
+	if err := o.Options.Complete(); err != nil {
+		return err
+	}
+
+	if len(args) != 1 {
+		return errors.New("an APIExport should be specified")
+	}
+	o.APIExport = args[0]
+	return nil
+}
+
+// Validate validates the BindComputeExplainOptions are complete and usable.
+func (o *BindComputeExplainOptions) Validate() error {
+	var errs []error
+
+	if err := o.Options.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if clusterName, _ := logicalcluster.New(o.APIExport).Split(); !isAbsoluteWorkspacePath(clusterName.String()) || !logicalcluster.New(o.APIExport).IsValid() {
+		errs = append(errs, fmt.Errorf("apiexport %q must be in the format <absolute_ref_to_workspace>:<apiexport>", o.APIExport))
+	}
+
+	if o.LocationWorkspace != "" && (!isAbsoluteWorkspacePath(o.LocationWorkspace) || !logicalcluster.New(o.LocationWorkspace).IsValid()) {
+		errs = append(errs, fmt.Errorf("location workspace %q must be an absolute workspace path like root:mylocations", o.LocationWorkspace))
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// Run prints the resources offered by the APIExport, and the SyncTargets of the location workspace supporting it.
+// Nothing is created or modified.
+func (o *BindComputeExplainOptions) Run(ctx context.Context) error {
+	_, kcpClient, err := o.KcpClients()
+	if err != nil {
+		return err
+	}
+
+	return o.explain(ctx, kcpClient)
+}
+
+// explain prints the resources offered by the APIExport, from its latest resource schemas, and with
+// --location-workspace the SyncTargets supporting it.
+func (o *BindComputeExplainOptions) explain(ctx context.Context, kcpClient kcpclient.ClusterInterface) error {
+	clusterName, name := logicalcluster.New(o.APIExport).Split()
+	apiExport, err := kcpClient.Cluster(clusterName).ApisV1alpha1().APIExports().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get apiexport %s: %w", o.APIExport, err)
+	}
+
+	if _, err := fmt.Fprintf(o.Out, "apiexport %s offers %d resource(s):\n", o.APIExport, len(apiExport.Spec.LatestResourceSchemas)); err != nil {
+		return err
+	}
+	for _, schemaName := range apiExport.Spec.LatestResourceSchemas {
+		// resource schemas live in the workspace of the APIExport.
+		schema, err := kcpClient.Cluster(clusterName).ApisV1alpha1().APIResourceSchemas().Get(ctx, schemaName, metav1.GetOptions{})
+		if err != nil {
+			if _, err := fmt.Fprintf(o.Out, "  %s: unknown: %v\n", schemaName, err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		resource := schema.Spec.Names.Plural
+		if len(schema.Spec.Group) > 0 {
+			resource += "." + schema.Spec.Group
+		}
+		var versions []string
+		for _, version := range schema.Spec.Versions {
+			versions = append(versions, version.Name)
+		}
+		if _, err := fmt.Fprintf(o.Out, "  %s: kind %s, %s, versions %s\n", resource, schema.Spec.Names.Kind, schema.Spec.Scope, strings.Join(versions, ",")); err != nil {
+			return err
+		}
+	}
+
+	if len(o.LocationWorkspace) == 0 {
+		return nil
+	}
+
+	locationWorkspace := logicalcluster.New(o.LocationWorkspace)
+	syncTargets, err := listSyncTargets(ctx, kcpClient.Cluster(locationWorkspace), "")
+	if err != nil {
+		return fmt.Errorf("failed to list synctargets in workspace %s: %w", locationWorkspace, err)
+	}
+	var supporting []string
+	for _, syncTarget := range syncTargets {
+		if syncTargetsAPIExports([]workloadv1alpha1.SyncTarget{syncTarget}, locationWorkspace).Has(o.APIExport) {
+			supporting = append(supporting, syncTarget.Name)
+		}
+	}
+	if len(supporting) == 0 {
+		_, err := fmt.Fprintf(o.Out, "no synctarget in workspace %s supports apiexport %s.\n", locationWorkspace, o.APIExport)
+		return err
+	}
+	_, err = fmt.Fprintf(o.Out, "%d synctarget(s) in workspace %s support apiexport %s: %s\n", len(supporting), locationWorkspace, o.APIExport, strings.Join(supporting, ","))
+	return err
+}
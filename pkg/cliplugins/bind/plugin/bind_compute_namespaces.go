@@ -0,0 +1,143 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// labelsNamespaces returns whether namespaces are labeled to match the namespace selector.
+func (o *BindComputeOptions) labelsNamespaces() bool {
+	return len(o.LabelMatchingNamespaces) > 0 || o.LabelAllNamespaces
+}
+
+// newNamespaceClient returns a client for the namespaces of the current workspace, or of --target-workspace.
+func (o *BindComputeOptions) newNamespaceClient() (kubernetes.Interface, error) {
+	config, kcpConfig, err := o.clientConfigs()
+	if err != nil {
+		return nil, err
+	}
+	if !o.targetWorkspace.Empty() {
+		config = rest.CopyConfig(kcpConfig)
+		config.Host += o.targetWorkspace.Path()
+	}
+	return kubernetes.NewForConfig(config)
+}
+
+// confirmLabelNamespaces asks the user to confirm labeling the namespaces, unless --yes is given.
+func (o *BindComputeOptions) confirmLabelNamespaces() (bool, error) {
+	if o.Yes {
+		return true, nil
+	}
+
+	matchLabels, err := o.namespaceMatchLabels()
+	if err != nil {
+		return false, err
+	}
+	namespaces := "all namespaces"
+	if !o.LabelAllNamespaces {
+		namespaces = "namespace(s) " + strings.Join(o.LabelMatchingNamespaces, ", ")
+	}
+	return confirm(o.IOStreams, fmt.Sprintf("Label %s in the %s with %s?", namespaces, o.targetDescription(), matchLabels))
+}
+
+// namespaceMatchLabels returns the match labels of the namespace selectors of all placements, which namespaces are
+// labeled with to match all of them. Placements requiring different values of the same label cannot be matched by
+// the same namespaces.
+func (o *BindComputeOptions) namespaceMatchLabels() (labels.Set, error) {
+	matchLabels := labels.Set{}
+	placementOf := map[string]string{}
+	for _, po := range o.placementOptions() {
+		if po.namespaceSelector == nil {
+			continue
+		}
+		for _, key := range sets.StringKeySet(po.namespaceSelector.MatchLabels).List() {
+			value := po.namespaceSelector.MatchLabels[key]
+			if existing, ok := matchLabels[key]; ok && existing != value {
+				return nil, fmt.Errorf("placements %s and %s require different values of namespace label %s, namespaces cannot be labeled to match both",
+					placementOf[key], po.PlacementName, key)
+			}
+			matchLabels[key] = value
+			placementOf[key] = po.PlacementName
+		}
+	}
+	return matchLabels, nil
+}
+
+// labelNamespaces applies the match labels of the namespace selectors of the placements to the namespaces given with
+// --label-matching-namespaces, or to all namespaces of the workspace with --label-all-namespaces, so that they match
+// right away.
+func (o *BindComputeOptions) labelNamespaces(ctx context.Context, client kubernetes.Interface) error {
+	matchLabels, err := o.namespaceMatchLabels()
+	if err != nil {
+		return err
+	}
+
+	names := o.LabelMatchingNamespaces
+	if o.LabelAllNamespaces {
+		names = nil
+		opts := metav1.ListOptions{Limit: listPageSize}
+		for {
+			list, err := client.CoreV1().Namespaces().List(ctx, opts)
+			if err != nil {
+				return err
+			}
+			for _, namespace := range list.Items {
+				names = append(names, namespace.Name)
+			}
+			if len(list.Continue) == 0 {
+				break
+			}
+			opts.Continue = list.Continue
+		}
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": matchLabels,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	labeled := 0
+	for _, name := range names {
+		if _, err := client.CoreV1().Namespaces().Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			errs = append(errs, fmt.Errorf("failed to label namespace %s: %w", name, err))
+			continue
+		}
+		labeled++
+	}
+
+	if _, err := fmt.Fprintf(o.Out, "labeled %d namespace(s) with %s.\n", labeled, matchLabels); err != nil {
+		errs = append(errs, err)
+	}
+	return utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,478 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/printers"
+	"sigs.k8s.io/yaml"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	kcpscheme "github.com/kcp-dev/kcp/pkg/client/clientset/versioned/scheme"
+)
+
+// objectYAML returns the YAML manifest of the given object with its apiVersion and kind set, and without status.
+func objectYAML(obj runtime.Object) ([]byte, error) {
+	content, err := objectContent(obj)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(content)
+}
+
+// objectContent returns the unstructured content of the given object with its apiVersion and kind set, and without
+// status.
+func objectContent(obj runtime.Object) (map[string]interface{}, error) {
+	gvks, _, err := kcpscheme.Scheme.ObjectKinds(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(gvks[0])
+	unstructured.RemoveNestedField(u.Object, "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(u.Object, "status")
+
+	return u.Object, nil
+}
+
+// applyPatch returns the server-side apply patch of the given object, with its apiVersion and kind set.
+func applyPatch(obj runtime.Object) ([]byte, error) {
+	content, err := objectContent(obj)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(content)
+}
+
+// printObjects serializes the given objects in the requested output format, to the output file if one is set or
+// to out otherwise.
+func (o *BindComputeOptions) printObjects(out io.Writer, objs []runtime.Object) error {
+	var buf bytes.Buffer
+	switch o.Output {
+	case "yaml":
+		for _, obj := range objs {
+			manifest, err := objectYAML(obj)
+			if err != nil {
+				return err
+			}
+			buf.WriteString("---\n")
+			buf.Write(manifest)
+		}
+	default:
+		return fmt.Errorf("unsupported output format %q", o.Output)
+	}
+
+	return o.writeOutput(out, buf.Bytes())
+}
+
+// printNameVars prints the names of the placement and of the APIBindings as shell variable assignments, to be
+// evaluated with eval, to the output file if one is set or to out otherwise.
+func (o *BindComputeOptions) printNameVars(out io.Writer, placement *schedulingv1alpha1.Placement, bindings []*apisv1alpha1.APIBinding) error {
+	bindingNames := make([]string, 0, len(bindings))
+	for _, binding := range bindings {
+		bindingNames = append(bindingNames, binding.Name)
+	}
+	sort.Strings(bindingNames)
+
+	return o.writeOutput(out, []byte(fmt.Sprintf("PLACEMENT_NAME=%s\nBINDING_NAMES=%s\n", placement.Name, strings.Join(bindingNames, ","))))
+}
+
+// printPlacementRefs prints a reference to each of the named placements as <workspace path>/placements/<name>, the
+// workspace being the one the placements are created in.
+func (o *BindComputeOptions) printPlacementRefs(out io.Writer, placementNames []string) error {
+	workspace := o.targetWorkspace
+	if workspace.Empty() {
+		currentWorkspace, err := o.currentWorkspace()
+		if err != nil {
+			return err
+		}
+		workspace = currentWorkspace
+	}
+
+	for _, name := range placementNames {
+		if _, err := fmt.Fprintf(out, "%s/placements/%s\n", workspace, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeOutput writes the data to the output file if one is set, or to out otherwise.
+func (o *BindComputeOptions) writeOutput(out io.Writer, data []byte) error {
+	if len(o.OutputFile) == 0 {
+		_, err := out.Write(data)
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(o.OutputFile), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", o.OutputFile, err)
+	}
+	if err := os.WriteFile(o.OutputFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", o.OutputFile, err)
+	}
+	return nil
+}
+
+// serverPopulatedFields are the metadata fields populated by the server, which do not belong in a manifest.
+var serverPopulatedFields = []string{"uid", "resourceVersion", "generation", "managedFields", "selfLink", "creationTimestamp", "clusterName"}
+
+// scrubbedObjectYAML returns the YAML manifest of the given object like objectYAML, additionally stripped of the
+// fields populated by the server, so that it can be applied again as is.
+func scrubbedObjectYAML(obj runtime.Object) ([]byte, error) {
+	content, err := objectContent(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	u := &unstructured.Unstructured{Object: content}
+	for _, field := range serverPopulatedFields {
+		unstructured.RemoveNestedField(u.Object, "metadata", field)
+	}
+	if annotations := u.GetAnnotations(); annotations != nil {
+		delete(annotations, logicalcluster.AnnotationKey)
+		if len(annotations) == 0 {
+			annotations = nil
+		}
+		u.SetAnnotations(annotations)
+	}
+
+	return yaml.Marshal(u.Object)
+}
+
+// writeObjectFiles writes each of the given objects to its own <kind>-<name>.yaml file in --objects-dir, stripped of
+// the fields populated by the server.
+func (o *BindComputeOptions) writeObjectFiles(objs []runtime.Object) error {
+	if err := os.MkdirAll(o.ObjectsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", o.ObjectsDir, err)
+	}
+
+	for _, obj := range objs {
+		manifest, err := scrubbedObjectYAML(obj)
+		if err != nil {
+			return err
+		}
+
+		gvks, _, err := kcpscheme.Scheme.ObjectKinds(obj)
+		if err != nil {
+			return err
+		}
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(o.ObjectsDir, fmt.Sprintf("%s-%s.yaml", strings.ToLower(gvks[0].Kind), accessor.GetName()))
+		if err := os.WriteFile(path, manifest, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// printPlacementSpec prints the placement about to be created as YAML.
+func (o *BindComputeOptions) printPlacementSpec(placement *schedulingv1alpha1.Placement) error {
+	manifest, err := objectYAML(placement)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(o.Out, "# placement to be applied\n%s", manifest)
+	return err
+}
+
+// printSelectorDump prints the parsed namespace selector and location selectors of each placement in their canonical
+// form.
+func (o *BindComputeOptions) printSelectorDump() error {
+	for _, po := range o.placementOptions() {
+		if _, err := fmt.Fprintf(o.Out, "placement %s:\n  namespace selector: %s\n", po.PlacementName, metav1.FormatLabelSelector(po.namespaceSelector)); err != nil {
+			return err
+		}
+		for i := range po.locationSelectors {
+			if _, err := fmt.Fprintf(o.Out, "  location selector: %s\n", metav1.FormatLabelSelector(&po.locationSelectors[i])); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// printCommand prints the kubectl command equivalent to creating the given object.
+func (o *BindComputeOptions) printCommand(obj runtime.Object) error {
+	manifest, err := objectYAML(obj)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(o.Out, "kubectl create -f - <<EOF\n%sEOF\n", manifest)
+	return err
+}
+
+// BindComputeResult is the outcome of a bind, printed as a single JSON object with -o json, whatever the number of
+// APIBindings and placements and whether the bind succeeded or not. Its fields are stable, for automation to rely on.
+type BindComputeResult struct {
+	LocationWorkspace string           `json:"locationWorkspace"`
+	Placements        []BindComputeRef `json:"placements"`
+	APIBindings       []BindComputeRef `json:"apiBindings"`
+	Ready             bool             `json:"ready"`
+	Elapsed           metav1.Duration  `json:"elapsed"`
+	Warnings          []string         `json:"warnings"`
+	// Error is the error the bind failed with, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// BindComputeRef references an object created or found by the bind.
+type BindComputeRef struct {
+	Name string `json:"name"`
+	// APIExport is the APIExport referenced by an APIBinding, as <workspace path>:<name>.
+	APIExport string `json:"apiExport,omitempty"`
+}
+
+// warn prints a warning to stderr, and records it in the result of the run.
+func (o *BindComputeOptions) warn(format string, args ...interface{}) error {
+	warning := fmt.Sprintf(format, args...)
+	if o.result != nil {
+		o.result.Warnings = append(o.result.Warnings, warning)
+	}
+	_, err := fmt.Fprintf(o.ErrOut, "Warning: %s\n", warning)
+	return err
+}
+
+// observeResult records the given APIBindings and placements in the result of the run. The bind is ready if it is
+// ready with every placement.
+func (o *BindComputeOptions) observeResult(bindings []*apisv1alpha1.APIBinding, placements []*schedulingv1alpha1.Placement) {
+	o.result.Placements = []BindComputeRef{}
+	o.result.APIBindings = []BindComputeRef{}
+	o.result.Ready = len(placements) > 0
+	for _, placement := range placements {
+		o.result.Placements = append(o.result.Placements, BindComputeRef{Name: placement.Name})
+		if !o.bindReady(bindings, placement) {
+			o.result.Ready = false
+		}
+	}
+	for _, binding := range bindings {
+		ref := BindComputeRef{Name: binding.Name}
+		if binding.Spec.Reference.Workspace != nil {
+			ref.APIExport = exportReferenceKey(binding.Spec.Reference.Workspace)
+		}
+		o.result.APIBindings = append(o.result.APIBindings, ref)
+	}
+}
+
+// printResult prints the BindComputeResult of the run as JSON, to the output file if one is set or to out otherwise.
+func (o *BindComputeOptions) printResult(out io.Writer, result *BindComputeResult) error {
+	data, err := json.MarshalIndent(result, "", "    ")
+	if err != nil {
+		return err
+	}
+	return o.writeOutput(out, append(data, '\n'))
+}
+
+// failureReport is the state of a failed bind, written to --failure-report.
+type failureReport struct {
+	Error               string                  `json:"error"`
+	Phase               string                  `json:"phase"`
+	LocationWorkspace   string                  `json:"locationWorkspace"`
+	RequestedAPIExports []string                `json:"requestedAPIExports"`
+	SupportedAPIExports []string                `json:"supportedAPIExports"`
+	APIBindings         []apiBindingReport      `json:"apiBindings"`
+	Placement           *placementFailureReport `json:"placement,omitempty"`
+}
+
+// apiBindingReport is the readiness of an APIBinding in a failureReport or callbackPayload.
+type apiBindingReport struct {
+	Name                    string   `json:"name"`
+	APIExport               string   `json:"apiExport"`
+	Phase                   string   `json:"phase"`
+	Ready                   bool     `json:"ready"`
+	PendingPermissionClaims []string `json:"pendingPermissionClaims,omitempty"`
+}
+
+// placementFailureReport is the readiness of the placement in a failureReport.
+type placementFailureReport struct {
+	Name  string `json:"name"`
+	Phase string `json:"phase"`
+	Ready bool   `json:"ready"`
+}
+
+// newFailureReport returns the report of a bind that failed with the given error in the given phase. Any of the
+// supported exports, bindings and placement not resolved or created yet are left out.
+func (o *BindComputeOptions) newFailureReport(runErr error, phase string, supportedExports sets.String, bindings []*apisv1alpha1.APIBinding, placement *schedulingv1alpha1.Placement) *failureReport {
+	report := &failureReport{
+		Error:               runErr.Error(),
+		Phase:               phase,
+		LocationWorkspace:   o.LocationWorkspace.String(),
+		RequestedAPIExports: sets.NewString(o.APIExports...).List(),
+		SupportedAPIExports: supportedExports.List(),
+		APIBindings:         newAPIBindingReports(bindings),
+	}
+	if placement != nil {
+		report.Placement = &placementFailureReport{
+			Name:  placement.Name,
+			Phase: string(placement.Status.Phase),
+			Ready: o.placementReady(placement),
+		}
+	}
+	return report
+}
+
+// newAPIBindingReports returns the readiness of each of the given APIBindings.
+func newAPIBindingReports(bindings []*apisv1alpha1.APIBinding) []apiBindingReport {
+	reports := []apiBindingReport{}
+	for _, binding := range bindings {
+		bindingReport := apiBindingReport{
+			Name:                    binding.Name,
+			Phase:                   string(binding.Status.Phase),
+			Ready:                   binding.Status.Phase == apisv1alpha1.APIBindingPhaseBound,
+			PendingPermissionClaims: pendingPermissionClaims(binding),
+		}
+		if binding.Spec.Reference.Workspace != nil {
+			bindingReport.APIExport = exportReferenceKey(binding.Spec.Reference.Workspace)
+		}
+		reports = append(reports, bindingReport)
+	}
+	return reports
+}
+
+// writeFailureReport writes the report of the failed bind to --failure-report as JSON.
+func (o *BindComputeOptions) writeFailureReport(runErr error, phase string, supportedExports sets.String, bindings []*apisv1alpha1.APIBinding, placement *schedulingv1alpha1.Placement) error {
+	data, err := json.MarshalIndent(o.newFailureReport(runErr, phase, supportedExports, bindings, placement), "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize failure report: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(o.FailureReport), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", o.FailureReport, err)
+	}
+	if err := os.WriteFile(o.FailureReport, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", o.FailureReport, err)
+	}
+	return nil
+}
+
+// tableColumns are the columns of the table printed by printTable, in order.
+var tableColumns = []string{"NAME", "KIND", "EXPORT/SELECTORS", "STATUS"}
+
+// wideTableColumns are the columns added to tableColumns with -o wide.
+var wideTableColumns = []string{"AGE", "LOCATION-WORKSPACE", "NAMESPACE-SELECTOR", "LOCATION-RESOURCE"}
+
+// availableTableColumns returns the columns that can be printed, including the wide ones with -o wide.
+func (o *BindComputeOptions) availableTableColumns() []string {
+	if o.Output == "wide" {
+		return append(append([]string{}, tableColumns...), wideTableColumns...)
+	}
+	return tableColumns
+}
+
+// printTable prints a table of the APIBindings and the placement with the columns selected with --columns, in the
+// order given.
+func (o *BindComputeOptions) printTable(out io.Writer, bindings []*apisv1alpha1.APIBinding, placement *schedulingv1alpha1.Placement) error {
+	columns := o.availableTableColumns()
+	if len(o.Columns) > 0 {
+		columns = make([]string, 0, len(o.Columns))
+		for _, column := range o.Columns {
+			columns = append(columns, strings.ToUpper(column))
+		}
+	}
+
+	var rows []map[string]string
+	for _, binding := range bindings {
+		export := ""
+		if binding.Spec.Reference.Workspace != nil {
+			export = exportReferenceKey(binding.Spec.Reference.Workspace)
+		}
+		rows = append(rows, map[string]string{
+			"NAME":             binding.Name,
+			"KIND":             "APIBinding",
+			"EXPORT/SELECTORS": export,
+			"STATUS":           valueOrUnknown(string(binding.Status.Phase)),
+			"AGE":              objectAge(binding.CreationTimestamp),
+		})
+	}
+	locationSelectors := make([]string, 0, len(placement.Spec.LocationSelectors))
+	for i := range placement.Spec.LocationSelectors {
+		locationSelectors = append(locationSelectors, metav1.FormatLabelSelector(&placement.Spec.LocationSelectors[i]))
+	}
+	placementStatus := "Ready"
+	if !o.placementReady(placement) {
+		placementStatus = "NotReady"
+	}
+	rows = append(rows, map[string]string{
+		"NAME":               placement.Name,
+		"KIND":               "Placement",
+		"EXPORT/SELECTORS":   fmt.Sprintf("namespaces=%s locations=%s", metav1.FormatLabelSelector(placement.Spec.NamespaceSelector), strings.Join(locationSelectors, ";")),
+		"STATUS":             placementStatus,
+		"AGE":                objectAge(placement.CreationTimestamp),
+		"LOCATION-WORKSPACE": placement.Spec.LocationWorkspace,
+		"NAMESPACE-SELECTOR": metav1.FormatLabelSelector(placement.Spec.NamespaceSelector),
+		"LOCATION-RESOURCE":  formatLocationResource(placement.Spec.LocationResource),
+	})
+
+	w := printers.GetNewTabWriter(out)
+	if !o.NoHeaders {
+		if _, err := fmt.Fprintln(w, strings.Join(columns, "\t")); err != nil {
+			return err
+		}
+	}
+	for _, row := range rows {
+		values := make([]string, 0, len(columns))
+		for _, column := range columns {
+			values = append(values, row[column])
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(values, "\t")); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// objectAge returns the time elapsed since the given creation timestamp, the way kubectl prints it.
+func objectAge(creationTimestamp metav1.Time) string {
+	if creationTimestamp.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(time.Since(creationTimestamp.Time))
+}
+
+// valueOrUnknown returns the value, or <unknown> if it is empty.
+func valueOrUnknown(value string) string {
+	if len(value) == 0 {
+		return "<unknown>"
+	}
+	return value
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/homedir"
+	"sigs.k8s.io/yaml"
+)
+
+// bindComputeProfiles is the content of the profiles file, e.g.:
+//
+//	profiles:
+//	  prod:
+//	    apiExports:
+//	    - root:compute:kubernetes
+//	    namespaceSelector: kcp.dev/workload=true
+//	    locationSelectors:
+//	    - env=prod
+//	    timeout: 2m
+type bindComputeProfiles struct {
+	Profiles map[string]bindComputeProfile `json:"profiles"`
+}
+
+// bindComputeProfile is a named set of defaults for the bind compute flags.
+type bindComputeProfile struct {
+	APIExports        []string         `json:"apiExports,omitempty"`
+	NamespaceSelector string           `json:"namespaceSelector,omitempty"`
+	LocationSelectors []string         `json:"locationSelectors,omitempty"`
+	Timeout           *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// defaultProfilesFile returns the path of the profiles file used without --profiles-file.
+func defaultProfilesFile() string {
+	return filepath.Join(homedir.HomeDir(), ".kcp", "bind-compute-profiles.yaml")
+}
+
+// applyProfile loads the profile named by --profile from the profiles file, and sets the options it defines unless
+// the corresponding flag was given explicitly.
+func (o *BindComputeOptions) applyProfile() error {
+	profilesFile := o.ProfilesFile
+	if len(profilesFile) == 0 {
+		profilesFile = defaultProfilesFile()
+	}
+	data, err := os.ReadFile(profilesFile)
+	if err != nil {
+		return fmt.Errorf("failed to read profiles file: %w", err)
+	}
+	var profiles bindComputeProfiles
+	if err := yaml.UnmarshalStrict(data, &profiles); err != nil {
+		return fmt.Errorf("failed to parse profiles file %s: %w", profilesFile, err)
+	}
+
+	profile, ok := profiles.Profiles[o.Profile]
+	if !ok {
+		return fmt.Errorf("profile %q not found in %s, must be one of %s", o.Profile, profilesFile, strings.Join(sets.StringKeySet(profiles.Profiles).List(), ", "))
+	}
+
+	if len(profile.APIExports) > 0 && !o.flagChanged("apiexports") {
+		o.APIExports = profile.APIExports
+	}
+	if len(profile.NamespaceSelector) > 0 && !o.flagChanged("namespace-selector") {
+		o.NamespaceSelectorString = profile.NamespaceSelector
+	}
+	if len(profile.LocationSelectors) > 0 && !o.flagChanged("location-selectors") {
+		o.LocationSelectorsStrings = profile.LocationSelectors
+	}
+	if profile.Timeout != nil && !o.flagChanged("timeout") {
+		o.BindWaitTimeout = profile.Timeout.Duration
+	}
+	return nil
+}
+
+// envDefaults maps the environment variables the flags default to, when neither they nor the flags they are mutually
+// exclusive with are given explicitly.
+var envDefaults = []struct {
+	env       string
+	flag      string
+	conflicts []string
+}{
+	{env: "KCP_BIND_APIEXPORTS", flag: "apiexports"},
+	{env: "KCP_BIND_LOCATION_SELECTORS", flag: "location-selectors", conflicts: []string{"all-locations"}},
+	{env: "KCP_BIND_TIMEOUT", flag: "timeout"},
+}
+
+// applyEnvironment sets the options of the flags not given explicitly from their environment variables, parsed the
+// same way as on the command line. The flags are not marked as changed, so that the environment only provides
+// defaults: explicit flags and --profile take precedence over it.
+func (o *BindComputeOptions) applyEnvironment() error {
+	if o.flags == nil {
+		return nil
+	}
+	for _, envDefault := range envDefaults {
+		value, ok := os.LookupEnv(envDefault.env)
+		flag := o.flags.Lookup(envDefault.flag)
+		if !ok || flag == nil || o.flagChanged(envDefault.flag) {
+			continue
+		}
+		conflicting := false
+		for _, conflict := range envDefault.conflicts {
+			conflicting = conflicting || o.flagChanged(conflict)
+		}
+		if conflicting {
+			continue
+		}
+		if err := flag.Value.Set(value); err != nil {
+			return fmt.Errorf("invalid value %q of %s for --%s: %w", value, envDefault.env, envDefault.flag, err)
+		}
+	}
+	return nil
+}
+
+// flagChanged returns whether the flag with the given name was set on the command line.
+func (o *BindComputeOptions) flagChanged(name string) bool {
+	return o.flags != nil && o.flags.Changed(name)
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+)
+
+// BindReconcileFlags binds the fields used by the reconcile subcommand as command line flags to cmd's flagset.
+func (o *BindComputeOptions) BindReconcileFlags(cmd *cobra.Command) {
+	o.Options.BindFlags(cmd)
+	o.bindSelectionFlags(cmd)
+
+	cmd.Flags().BoolVar(&o.Prune, "prune", o.Prune, "Delete APIBindings created by bind compute whose APIExport is no longer supported by the synctargets.")
+	cmd.Flags().DurationVar(&o.ResyncInterval, "resync-interval", o.ResyncInterval, "Interval at which the APIBindings are reconciled even if no synctarget changed.")
+	o.bindClientFlags(cmd)
+}
+
+// RunReconcile keeps the APIBindings of the workspace in sync with the APIExports supported by the SyncTargets in the
+// location workspace, until the context is done. The APIBindings are reconciled whenever a SyncTarget changes, and
+// every --resync-interval. Transient failures are reported and retried on the next reconciliation, while other
+// failures stop the reconciliation and are returned.
+func (o *BindComputeOptions) RunReconcile(ctx context.Context) error {
+	userWorkspaceKcpClient, kcpClient, err := o.newClients()
+	if err != nil {
+		return err
+	}
+
+	return o.reconcileLoop(ctx, userWorkspaceKcpClient, kcpClient.Cluster(o.LocationWorkspace))
+}
+
+// reconcileLoop reconciles the APIBindings until the context is done, or the reconciliation fails for a reason
+// retrying will not resolve.
+func (o *BindComputeOptions) reconcileLoop(ctx context.Context, client kcpclient.Interface, locationClient kcpclient.Interface) error {
+	for {
+		if err := o.reconcileAPIBindings(ctx, client, locationClient); err != nil && ctx.Err() == nil {
+			if !isTransientReconcileError(err) {
+				return fmt.Errorf("failed to reconcile APIBindings: %w", err)
+			}
+			fmt.Fprintf(o.ErrOut, "failed to reconcile APIBindings: %v\n", err)
+		}
+
+		o.waitForSyncTargetChange(ctx, locationClient)
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// isTransientReconcileError returns whether the next reconciliation might succeed after one failed with the given
+// error. The SyncTargets coming and going is what is reconciled, so their absence is transient as well.
+func isTransientReconcileError(err error) bool {
+	var noSyncTargets *NoSyncTargetsError
+	return IsRetryableError(err) || errors.As(err, &noSyncTargets)
+}
+
+// reconcileAPIBindings binds every APIExport currently supported by the SyncTargets, and with --prune deletes the
+// APIBindings of those no longer supported. APIBindings that failed for good, e.g. as their APIExport does not exist,
+// fail the reconciliation.
+func (o *BindComputeOptions) reconcileAPIBindings(ctx context.Context, client kcpclient.Interface, locationClient kcpclient.Interface) error {
+	supportedExports, err := o.supportedAPIExports(ctx, locationClient)
+	if err != nil {
+		return err
+	}
+	bindings, err := o.applyAPIBinding(ctx, client, supportedExports, nil)
+	if err != nil {
+		return err
+	}
+	return utilerrors.NewAggregate(terminalBindingFailures(bindings))
+}
+
+// waitForSyncTargetChange returns once a SyncTarget in the location workspace changes, --resync-interval elapses, or
+// the context is done.
+func (o *BindComputeOptions) waitForSyncTargetChange(ctx context.Context, locationClient kcpclient.Interface) {
+	ctx, cancel := context.WithTimeout(ctx, o.ResyncInterval)
+	defer cancel()
+
+	// watch from the current resource version, so that existing SyncTargets are not reported as added.
+	list, err := locationClient.WorkloadV1alpha1().SyncTargets().List(ctx, metav1.ListOptions{LabelSelector: o.SyncTargetSelector, Limit: 1})
+	if err != nil {
+		<-ctx.Done()
+		return
+	}
+	watcher, err := locationClient.WorkloadV1alpha1().SyncTargets().Watch(ctx, metav1.ListOptions{LabelSelector: o.SyncTargetSelector, ResourceVersion: list.ResourceVersion})
+	if err != nil {
+		<-ctx.Done()
+		return
+	}
+	defer watcher.Stop()
+
+	select {
+	case <-ctx.Done():
+	case _, ok := <-watcher.ResultChan():
+		if !ok {
+			// the watch was closed by the server, fall back to the resync interval.
+			<-ctx.Done()
+		}
+	}
+}
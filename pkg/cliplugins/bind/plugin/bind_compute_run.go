@@ -0,0 +1,745 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+)
+
+// RunWithRetries runs the bind up to Attempts times, as long as it fails with a retryable error. The bind is
+// idempotent, so it is safe to run it again as a whole. The result of the last attempt only is reported with
+// -o json, --failure-report, --debug-dump-on-failure and --callback-url.
+func (o *BindComputeOptions) RunWithRetries(ctx context.Context) error {
+	o.deferReport = true
+	defer func() {
+		o.deferReport = false
+		o.reportRun()
+	}()
+
+	for attempt := 1; ; attempt++ {
+		err := o.Run(ctx)
+		if err == nil || attempt >= o.Attempts || !IsRetryableError(err) {
+			return err
+		}
+
+		fmt.Fprintf(o.ErrOut, "attempt %d of %d failed, retrying in %s: %v\n", attempt, o.Attempts, o.AttemptDelay, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(o.AttemptDelay):
+		}
+	}
+}
+
+// runState is the state a run of the bind ended in, reported once the bind is done.
+type runState struct {
+	err              error
+	phase            string
+	supportedExports sets.String
+	bindings         []*apisv1alpha1.APIBinding
+	placement        *schedulingv1alpha1.Placement
+	result           *BindComputeResult
+}
+
+// reportRun reports the last run of the bind, and of each bind request of the batch, with -o json, --failure-report,
+// --debug-dump-on-failure and --callback-url.
+func (o *BindComputeOptions) reportRun() {
+	for _, entry := range o.batch {
+		// the output of the bind request was flushed already.
+		entry.IOStreams = o.IOStreams
+		entry.reportRun()
+	}
+	run := o.lastRun
+	if run == nil {
+		return
+	}
+	o.lastRun = nil
+
+	if o.Output == "json" {
+		if err := o.printResult(o.Out, run.result); err != nil {
+			fmt.Fprintf(o.ErrOut, "Warning: %v\n", err)
+		}
+	}
+	if len(o.CallbackURL) > 0 {
+		if err := o.postCallback(run.err, run.bindings); err != nil {
+			fmt.Fprintf(o.ErrOut, "Warning: %v\n", err)
+		}
+	}
+	if run.err == nil {
+		return
+	}
+	if len(o.DebugDumpOnFailure) > 0 {
+		if err := o.writeDebugDump(run.supportedExports); err != nil {
+			fmt.Fprintf(o.ErrOut, "Warning: %v\n", err)
+		}
+	}
+	if len(o.FailureReport) > 0 {
+		if err := o.writeFailureReport(run.err, run.phase, run.supportedExports, run.bindings, run.placement); err != nil {
+			fmt.Fprintf(o.ErrOut, "Warning: %v\n", err)
+		}
+	}
+}
+
+// Run creates a placement in the workspace, linking to the location workspace
+func (o *BindComputeOptions) Run(ctx context.Context) (err error) {
+	if o.wildcard {
+		_, kcpClient, err := o.newClients()
+		if err != nil {
+			return err
+		}
+		if err := o.bindWildcard(ctx, kcpClient); err != nil {
+			return err
+		}
+	}
+	if len(o.batch) > 0 {
+		return o.runBatch(ctx)
+	}
+
+	start := time.Now()
+	o.result = &BindComputeResult{
+		LocationWorkspace: o.LocationWorkspace.String(),
+		Placements:        []BindComputeRef{},
+		APIBindings:       []BindComputeRef{},
+		Warnings:          []string{},
+	}
+
+	// with --deadline, every phase shares the same budget, and the phase running out of it is reported.
+	phase := "creating clients"
+
+	var (
+		supportedExports sets.String
+		bindings         []*apisv1alpha1.APIBinding
+		placements       []*schedulingv1alpha1.Placement
+	)
+	// the run is reported whatever its outcome, once stdout is restored.
+	defer func() {
+		o.result.Elapsed = metav1.Duration{Duration: time.Since(start).Round(time.Millisecond)}
+		if err != nil {
+			o.observeResult(bindings, placements)
+			o.result.Ready = false
+			o.result.Error = err.Error()
+		}
+		// the placement the bind failed on, if any, is the last one.
+		var placement *schedulingv1alpha1.Placement
+		if len(placements) > 0 {
+			placement = placements[len(placements)-1]
+		}
+		o.lastRun = &runState{err: err, phase: phase, supportedExports: supportedExports, bindings: bindings, placement: placement, result: o.result}
+		if !o.deferReport {
+			o.reportRun()
+		}
+	}()
+
+	// with -o name-vars, -o json and --print-ref, stdout is meant to be parsed, so progress messages go to stderr.
+	stdout := o.Out
+	if ((o.Output == "name-vars" || o.Output == "json") && len(o.OutputFile) == 0) || o.PrintRef {
+		o.Out = o.ErrOut
+		defer func() { o.Out = stdout }()
+	}
+
+	if o.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.Deadline)
+		defer cancel()
+		defer func() {
+			if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				err = fmt.Errorf("deadline of %s exceeded while %s: %w", o.Deadline, phase, err)
+			}
+		}()
+	}
+	// on an interactive terminal, the current phase is shown behind a spinner.
+	var phaseSpinner *spinner
+	if isTerminal(o.Out) {
+		out := o.Out
+		phaseSpinner = newSpinner(out)
+		o.Out = phaseSpinner
+		if stdout == out {
+			stdout = phaseSpinner
+		}
+		phaseSpinner.SetPhase(phase)
+		phaseSpinner.Start(spinnerInterval)
+		defer func() {
+			phaseSpinner.Stop()
+			o.Out = out
+		}()
+	}
+	enterPhase := func(name string) error {
+		phase = name
+		if phaseSpinner != nil {
+			phaseSpinner.SetPhase(name)
+		}
+		return ctx.Err()
+	}
+
+	userWorkspaceKcpClient, kcpClient, err := o.newClients()
+	if err != nil {
+		return err
+	}
+
+	if err := o.checkServer(ctx, userWorkspaceKcpClient, kcpClient, enterPhase); err != nil {
+		return err
+	}
+	if o.SelectorPreview {
+		return o.printSelectorPreview(ctx, kcpClient.Cluster(o.LocationWorkspace))
+	}
+	if err := o.checkTarget(ctx, kcpClient, enterPhase); err != nil {
+		return err
+	}
+
+	if o.IfNotExists {
+		if err := enterPhase("checking for an existing placement"); err != nil {
+			return err
+		}
+		if exist, err := o.placementsExist(ctx, userWorkspaceKcpClient, stdout); exist || err != nil {
+			return err
+		}
+	}
+
+	if o.labelsNamespaces() {
+		confirmed, err := o.confirmLabelNamespaces()
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			_, err := fmt.Fprintln(o.Out, "aborted.")
+			return err
+		}
+		if o.namespaceClient, err = o.newNamespaceClient(); err != nil {
+			return err
+		}
+	}
+
+	if supportedExports, err = o.resolveAPIExports(ctx, kcpClient, enterPhase); err != nil {
+		return err
+	}
+
+	if o.OutputStatusOnly {
+		if err := enterPhase("waiting for readiness"); err != nil {
+			return err
+		}
+		bindings, placements, err = o.waitForExistingBinds(ctx, userWorkspaceKcpClient, supportedExports, start)
+		if err != nil {
+			return err
+		}
+		o.observeResult(bindings, placements)
+		return nil
+	}
+
+	if o.NoWaitOnExisting {
+		if err := enterPhase("checking for an existing bind"); err != nil {
+			return err
+		}
+		readyBindings, readyPlacements, ready, err := o.existingReadyBinds(ctx, userWorkspaceKcpClient, supportedExports)
+		if err != nil {
+			return err
+		}
+		if ready {
+			bindings, placements = readyBindings, readyPlacements
+			o.observeResult(bindings, placements)
+			return o.printOutputs(ctx, stdout, kcpClient.Cluster(o.LocationWorkspace), bindings, placements)
+		}
+	}
+
+	bindings, placements, err = o.createBind(ctx, stdout, userWorkspaceKcpClient, kcpClient, supportedExports, start, enterPhase)
+	return err
+}
+
+// checkServer checks that the current and the location workspaces can be reached and serve the kcp APIs bind compute
+// uses, once the location workspace is discovered and the selectors are copied if requested.
+func (o *BindComputeOptions) checkServer(ctx context.Context, userWorkspaceKcpClient kcpclient.Interface, kcpClient kcpclient.ClusterInterface, enterPhase func(string) error) error {
+	if o.DiscoverLocation {
+		if err := enterPhase("discovering the location workspace"); err != nil {
+			return err
+		}
+		if err := o.discoverLocationWorkspace(ctx, kcpClient); err != nil {
+			return err
+		}
+		o.result.LocationWorkspace = o.LocationWorkspace.String()
+	}
+
+	if len(o.SelectorsFrom) > 0 {
+		// the placement is read from the workspace the new one is created in.
+		if err := enterPhase("copying the selectors"); err != nil {
+			return err
+		}
+		if err := o.copySelectorsFrom(ctx, userWorkspaceKcpClient); err != nil {
+			return err
+		}
+	}
+
+	if o.SelectorDump {
+		if err := o.printSelectorDump(); err != nil {
+			return err
+		}
+	}
+
+	if o.Preflight {
+		if err := enterPhase("checking connectivity"); err != nil {
+			return err
+		}
+		userWorkspaceURL, locationWorkspaceURL, err := o.workspaceURLs()
+		if err != nil {
+			return err
+		}
+		if err := checkReachable(ctx, userWorkspaceKcpClient, "current workspace", userWorkspaceURL); err != nil {
+			return err
+		}
+		if err := checkReachable(ctx, kcpClient.Cluster(o.LocationWorkspace), "location workspace", locationWorkspaceURL); err != nil {
+			return err
+		}
+	}
+
+	if err := enterPhase("checking the kcp APIs"); err != nil {
+		return err
+	}
+	if err := checkAPIGroups(userWorkspaceKcpClient.Discovery(), userWorkspaceAPIs); err != nil {
+		return err
+	}
+	if err := checkAPIGroups(kcpClient.Cluster(o.LocationWorkspace).Discovery(), locationWorkspaceAPIs); err != nil {
+		return err
+	}
+	return o.checkLocationResource(kcpClient.Cluster(o.LocationWorkspace).Discovery())
+}
+
+// checkTarget checks that the bind is allowed in the workspace it creates the objects in, and records the source of
+// the objects with --annotate-with-source.
+func (o *BindComputeOptions) checkTarget(ctx context.Context, kcpClient kcpclient.ClusterInterface, enterPhase func(string) error) error {
+	if err := o.checkProtectedWorkspace(); err != nil {
+		return err
+	}
+
+	if o.AnnotateWithSource {
+		var err error
+		if o.annotations, err = o.sourceAnnotations(); err != nil {
+			return err
+		}
+	}
+
+	if !o.targetWorkspace.Empty() {
+		if err := enterPhase("checking the target workspace"); err != nil {
+			return err
+		}
+		if err := o.checkTargetWorkspace(ctx, kcpClient); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// placementsExist returns whether all placements to create already exist, with --if-not-exists, in which case they
+// are reported as bound and nothing else is done.
+func (o *BindComputeOptions) placementsExist(ctx context.Context, client kcpclient.Interface, stdout io.Writer) (bool, error) {
+	existing, err := o.existingPlacements(ctx, client)
+	if err != nil {
+		return false, err
+	}
+	if len(existing) != len(o.placementOptions()) {
+		return false, nil
+	}
+
+	for _, name := range existing {
+		if _, err := fmt.Fprintf(o.Out, "placement %s already exists, already bound.\n", name); err != nil {
+			return true, err
+		}
+		o.result.Placements = append(o.result.Placements, BindComputeRef{Name: name})
+	}
+	o.result.Ready = true
+	if o.PrintRef {
+		return true, o.printPlacementRefs(stdout, existing)
+	}
+	return true, nil
+}
+
+// resolveAPIExports returns the APIExports to bind, supported by the SyncTargets of the location workspace, and
+// verifies their identities and endpoints if requested.
+func (o *BindComputeOptions) resolveAPIExports(ctx context.Context, kcpClient kcpclient.ClusterInterface, enterPhase func(string) error) (sets.String, error) {
+	if err := enterPhase("resolving supported APIExports"); err != nil {
+		return nil, err
+	}
+	if !o.apiExportsFromWorkspace.Empty() {
+		var err error
+		if o.workspaceAPIExports, err = listWorkspaceAPIExports(ctx, kcpClient.Cluster(o.apiExportsFromWorkspace), o.apiExportsFromWorkspace); err != nil {
+			return nil, err
+		}
+	}
+	supportedExports, err := o.supportedAPIExports(ctx, kcpClient.Cluster(o.LocationWorkspace))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(o.expectedIdentities) > 0 {
+		if err := enterPhase("verifying APIExport identities"); err != nil {
+			return supportedExports, err
+		}
+		if err := o.verifyExportIdentities(ctx, kcpClient, supportedExports); err != nil {
+			return supportedExports, err
+		}
+	}
+
+	if o.VerifyExportEndpoints {
+		if err := enterPhase("verifying APIExport endpoints"); err != nil {
+			return supportedExports, err
+		}
+		if err := o.verifyExportEndpoints(ctx, kcpClient, supportedExports); err != nil {
+			return supportedExports, err
+		}
+	}
+	return supportedExports, nil
+}
+
+// waitForExistingBinds waits for the existing placements and the APIBindings to be ready, with --output-status-only.
+// The placement the wait failed on, if any, is returned last.
+func (o *BindComputeOptions) waitForExistingBinds(ctx context.Context, client kcpclient.Interface, supportedExports sets.String, start time.Time) ([]*apisv1alpha1.APIBinding, []*schedulingv1alpha1.Placement, error) {
+	var (
+		bindings   []*apisv1alpha1.APIBinding
+		placements []*schedulingv1alpha1.Placement
+	)
+	for _, po := range o.placementOptions() {
+		var placement *schedulingv1alpha1.Placement
+		var err error
+		bindings, placement, err = po.waitForExistingBind(ctx, client, supportedExports, start)
+		if placement != nil {
+			placements = append(placements, placement)
+		}
+		if err != nil {
+			return bindings, placements, err
+		}
+	}
+	return bindings, placements, nil
+}
+
+// existingReadyBinds returns the existing APIBindings and placements, and whether they are all there and ready, with
+// --no-wait-on-existing. A ready bind is reported as already bound.
+func (o *BindComputeOptions) existingReadyBinds(ctx context.Context, client kcpclient.Interface, supportedExports sets.String) ([]*apisv1alpha1.APIBinding, []*schedulingv1alpha1.Placement, bool, error) {
+	var (
+		bindings   []*apisv1alpha1.APIBinding
+		placements []*schedulingv1alpha1.Placement
+	)
+	for _, po := range o.placementOptions() {
+		existingBindings, existingPlacement, ready, err := po.existingReadyBind(ctx, client, supportedExports)
+		if err != nil || !ready {
+			return nil, nil, false, err
+		}
+		bindings = existingBindings
+		placements = append(placements, existingPlacement)
+	}
+
+	for _, placement := range placements {
+		if _, err := fmt.Fprintf(o.Out, "already bound %d APIExport(s) with placement %s.\n", len(bindings), placement.Name); err != nil {
+			return nil, nil, false, err
+		}
+	}
+	return bindings, placements, true, nil
+}
+
+// createBind creates the APIBindings and the placements, waits for them to be ready and prints the outputs. The
+// placement the bind failed on, if any, is returned last.
+func (o *BindComputeOptions) createBind(ctx context.Context, stdout io.Writer, userWorkspaceKcpClient kcpclient.Interface, kcpClient kcpclient.ClusterInterface,
+	supportedExports sets.String, start time.Time, enterPhase func(string) error) ([]*apisv1alpha1.APIBinding, []*schedulingv1alpha1.Placement, error) {
+	var permissionClaims map[string][]apisv1alpha1.AcceptablePermissionClaim
+	if len(o.AcceptPermissionClaims) > 0 {
+		if err := enterPhase("resolving permission claims"); err != nil {
+			return nil, nil, err
+		}
+		var err error
+		if permissionClaims, err = o.permissionClaimsToAccept(ctx, kcpClient, supportedExports); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := enterPhase("creating APIBindings"); err != nil {
+		return nil, nil, err
+	}
+	// with --continue-on-binding-error, the bind goes on with the APIBindings that were created, and fails in the end.
+	var partialErr error
+	bindings, err := o.applyAPIBinding(ctx, userWorkspaceKcpClient, supportedExports, permissionClaims)
+	var partialBindErr *PartialBindError
+	if errors.As(err, &partialBindErr) && len(bindings) > 0 {
+		partialErr = err
+	} else if err != nil {
+		return bindings, nil, err
+	}
+
+	if o.Verbose {
+		if err := o.printLocationWorkspaceType(ctx, kcpClient); err != nil {
+			return bindings, nil, err
+		}
+	}
+
+	// with --placement, each placement is created and waited for in turn, sharing the APIBindings.
+	var placements []*schedulingv1alpha1.Placement
+	for _, po := range o.placementOptions() {
+		var placement *schedulingv1alpha1.Placement
+		placement, bindings, err = po.bindPlacement(ctx, userWorkspaceKcpClient, kcpClient, bindings, start, enterPhase)
+		if placement != nil {
+			placements = append(placements, placement)
+		}
+		if err != nil {
+			return bindings, placements, err
+		}
+	}
+
+	if err := enterPhase("checking the bound APIExports"); err != nil {
+		return bindings, placements, err
+	}
+	if bindings, err = o.checkExportDrift(ctx, userWorkspaceKcpClient, supportedExports, bindings); err != nil {
+		return bindings, placements, err
+	}
+
+	o.observeResult(bindings, placements)
+	if err := o.printOutputs(ctx, stdout, kcpClient.Cluster(o.LocationWorkspace), bindings, placements); err != nil {
+		return bindings, placements, err
+	}
+	return bindings, placements, partialErr
+}
+
+// bindPlacement creates the placement and waits for it and the APIBindings to be ready. The current APIBindings are
+// returned along with the placement.
+func (o *BindComputeOptions) bindPlacement(ctx context.Context, userWorkspaceKcpClient kcpclient.Interface, kcpClient kcpclient.ClusterInterface,
+	bindings []*apisv1alpha1.APIBinding, start time.Time, enterPhase func(string) error) (*schedulingv1alpha1.Placement, []*apisv1alpha1.APIBinding, error) {
+	if err := enterPhase("creating the placement"); err != nil {
+		return nil, bindings, err
+	}
+	placement, err := o.applyPlacement(ctx, userWorkspaceKcpClient)
+	if err != nil {
+		return nil, bindings, err
+	}
+
+	if o.namespaceClient != nil {
+		if err := enterPhase("labeling namespaces"); err != nil {
+			return placement, bindings, err
+		}
+		if err := o.labelNamespaces(ctx, o.namespaceClient); err != nil {
+			return placement, bindings, err
+		}
+	}
+
+	if o.ExclusiveLocations {
+		if err := enterPhase("checking for overlapping placements"); err != nil {
+			return placement, bindings, err
+		}
+		if err := o.checkExclusiveLocations(ctx, userWorkspaceKcpClient, kcpClient.Cluster(o.LocationWorkspace), placement); err != nil {
+			return placement, bindings, err
+		}
+	}
+
+	// wait for bind to be ready
+	if err := enterPhase("waiting for readiness"); err != nil {
+		return placement, bindings, err
+	}
+	timings := &bindTimings{start: start}
+	timings.observe(bindings)
+	if !o.bindReady(bindings, placement) {
+		waitStart := time.Now()
+		if err := o.pollUntilReady(ctx, func(ctx context.Context) (done bool, err error) {
+			currentPlacement, err := userWorkspaceKcpClient.SchedulingV1alpha1().Placements().Get(ctx, placement.Name, metav1.GetOptions{})
+			if err != nil {
+				return false, err
+			}
+			var currentBindings []*apisv1alpha1.APIBinding
+			for _, binding := range bindings {
+				currentBinding, err := userWorkspaceKcpClient.ApisV1alpha1().APIBindings().Get(ctx, binding.Name, metav1.GetOptions{})
+				if err != nil {
+					return false, err
+				}
+				currentBindings = append(currentBindings, currentBinding)
+			}
+
+			placement, bindings = currentPlacement, currentBindings
+			timings.observe(bindings)
+			if o.Verbose {
+				// the elapsed time helps judging the progress against --timeout.
+				if err := o.printStatus(fmt.Sprintf("[%s] ", time.Since(waitStart).Round(time.Second)), bindings, placement); err != nil {
+					return false, err
+				}
+			}
+			if o.FailFast {
+				if err := o.terminalFailure(bindings, placement); err != nil {
+					return false, err
+				}
+			}
+			return o.bindReady(bindings, placement), nil
+		}); err != nil {
+			if err := o.printSummary(bindings, placement, time.Since(start)); err != nil {
+				return placement, bindings, err
+			}
+			return placement, bindings, fmt.Errorf("bind compute is not ready %s: %w", placement.Name, err)
+		}
+	}
+
+	if err := o.printSummary(bindings, placement, time.Since(start)); err != nil {
+		return placement, bindings, err
+	}
+	if o.Timings {
+		if _, err := fmt.Fprintln(o.Out, timings.String(time.Since(start))); err != nil {
+			return placement, bindings, err
+		}
+	}
+
+	if o.ShowSelectedLocations {
+		if err := o.printSelectedLocations(ctx, kcpClient.Cluster(o.LocationWorkspace), placement); err != nil {
+			return placement, bindings, err
+		}
+	}
+
+	return placement, bindings, nil
+}
+
+// printOutputs prints the APIBindings and the placements in the format of --output to stdout, and writes them to
+// --objects-dir. With --include-matched-locations, the Locations selected by the placements are printed as well.
+// With -o json, nothing is printed here, the BindComputeResult is printed once the run is reported. With --print-ref,
+// a reference to each placement is printed after them.
+func (o *BindComputeOptions) printOutputs(ctx context.Context, stdout io.Writer, locationClient kcpclient.Interface, bindings []*apisv1alpha1.APIBinding, placements []*schedulingv1alpha1.Placement) error {
+	var objs []runtime.Object
+	for _, placement := range placements {
+		objs = append(objs, placement)
+	}
+	for _, binding := range bindings {
+		objs = append(objs, binding)
+	}
+	if o.IncludeMatchedLocations {
+		locations, err := o.matchedLocations(ctx, locationClient, placements)
+		if err != nil {
+			return err
+		}
+		objs = append(objs, locations...)
+	}
+	switch o.Output {
+	case "yaml":
+		if err := o.printObjects(stdout, objs); err != nil {
+			return err
+		}
+	case "name-vars":
+		if err := o.printNameVars(stdout, placements[0], bindings); err != nil {
+			return err
+		}
+	}
+	if o.PrintRef {
+		placementNames := make([]string, 0, len(placements))
+		for _, placement := range placements {
+			placementNames = append(placementNames, placement.Name)
+		}
+		if err := o.printPlacementRefs(stdout, placementNames); err != nil {
+			return err
+		}
+	}
+	if len(o.ObjectsDir) > 0 {
+		if err := o.writeObjectFiles(objs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// existingPlacements returns the names of the placements to create that already exist.
+func (o *BindComputeOptions) existingPlacements(ctx context.Context, client kcpclient.Interface) ([]string, error) {
+	var existing []string
+	for _, po := range o.placementOptions() {
+		_, err := client.SchedulingV1alpha1().Placements().Get(ctx, po.PlacementName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		existing = append(existing, po.PlacementName)
+	}
+	return existing, nil
+}
+
+// existingReadyBind returns the existing APIBindings of the given APIExports and the existing placement, and whether
+// they are all there and ready. Nothing is created.
+func (o *BindComputeOptions) existingReadyBind(ctx context.Context, client kcpclient.Interface, exports sets.String) ([]*apisv1alpha1.APIBinding, *schedulingv1alpha1.Placement, bool, error) {
+	apiBindings, err := listAPIBindings(ctx, client)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	existingBindings := map[string]*apisv1alpha1.APIBinding{}
+	for i := range apiBindings {
+		if apiBindings[i].Spec.Reference.Workspace != nil {
+			existingBindings[exportReferenceKey(apiBindings[i].Spec.Reference.Workspace)] = &apiBindings[i]
+		}
+	}
+
+	var bindings []*apisv1alpha1.APIBinding
+	for _, export := range exports.List() {
+		binding, ok := existingBindings[export]
+		if !ok {
+			return nil, nil, false, nil
+		}
+		bindings = append(bindings, binding)
+	}
+	sort.Slice(bindings, func(i, j int) bool {
+		return bindings[i].Name < bindings[j].Name
+	})
+
+	placement, err := client.SchedulingV1alpha1().Placements().Get(ctx, o.PlacementName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil, false, nil
+	}
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	return bindings, placement, o.bindReady(bindings, placement), nil
+}
+
+// waitForExistingBind waits for the existing APIBindings of the given APIExports and the existing placement to be
+// ready, without creating anything, and prints the summary of the bind. They are waited for if they do not exist yet.
+func (o *BindComputeOptions) waitForExistingBind(ctx context.Context, client kcpclient.Interface, exports sets.String, start time.Time) ([]*apisv1alpha1.APIBinding, *schedulingv1alpha1.Placement, error) {
+	var bindings []*apisv1alpha1.APIBinding
+	var placement *schedulingv1alpha1.Placement
+	err := o.pollUntilReady(ctx, func(ctx context.Context) (bool, error) {
+		existingBindings, existingPlacement, ready, err := o.existingReadyBind(ctx, client, exports)
+		if err != nil || existingPlacement == nil {
+			return false, err
+		}
+		bindings, placement = existingBindings, existingPlacement
+		if o.Verbose && !ready {
+			if err := o.printStatus(fmt.Sprintf("[%s] ", time.Since(start).Round(time.Second)), bindings, placement); err != nil {
+				return false, err
+			}
+		}
+		return ready, nil
+	})
+	if placement == nil {
+		// never found, so the wait failed.
+		return nil, nil, fmt.Errorf("placement %s or the APIBindings of the APIExports do not exist: %w", o.PlacementName, err)
+	}
+	if err := o.printSummary(bindings, placement, time.Since(start)); err != nil {
+		return bindings, placement, err
+	}
+	if err != nil {
+		return bindings, placement, fmt.Errorf("bind compute is not ready %s: %w", placement.Name, err)
+	}
+	return bindings, placement, nil
+}
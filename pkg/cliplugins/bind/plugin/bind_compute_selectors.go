@@ -0,0 +1,81 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+)
+
+// copySelectorsFrom sets the namespace selector and the location selectors to those of the placement given with
+// --selectors-from, for each of them not given by a flag, and completes and validates the options again with them.
+func (o *BindComputeOptions) copySelectorsFrom(ctx context.Context, client kcpclient.Interface) error {
+	placement, err := client.SchedulingV1alpha1().Placements().Get(ctx, o.SelectorsFrom, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("placement %s to copy the selectors from not found in the %s", o.SelectorsFrom, o.targetDescription())
+	}
+	if err != nil {
+		return err
+	}
+
+	if !o.flagChanged("namespace-selector") && !o.flagChanged("all-namespaces") && !o.flagChanged("namespace-preset") {
+		if o.NamespaceSelectorString, err = selectorString(placement.Spec.NamespaceSelector); err != nil {
+			return fmt.Errorf("invalid namespace selector of placement %s: %w", placement.Name, err)
+		}
+	}
+	if !o.flagChanged("location-selectors") && !o.flagChanged("all-locations") && !o.flagChanged("location-match-labels") {
+		o.LocationSelectorsStrings = nil
+		for i := range placement.Spec.LocationSelectors {
+			locationSelector, err := selectorString(&placement.Spec.LocationSelectors[i])
+			if err != nil {
+				return fmt.Errorf("invalid location selector of placement %s: %w", placement.Name, err)
+			}
+			if locationSelector == labels.Everything().String() {
+				// blank selectors are dropped from the list, while this one makes the placement select all locations.
+				o.LocationSelectorsStrings = []string{locationSelector}
+				break
+			}
+			o.LocationSelectorsStrings = append(o.LocationSelectorsStrings, locationSelector)
+		}
+	}
+
+	if errs := o.completeSelectors(); len(errs) > 0 {
+		return fmt.Errorf("invalid selectors copied from placement %s: %w", placement.Name, utilerrors.NewAggregate(errs))
+	}
+	o.defaultPlacementNames()
+	return o.Validate()
+}
+
+// selectorString returns the given label selector in the format of the selector flags, the empty string matching
+// everything.
+func selectorString(selector *metav1.LabelSelector) (string, error) {
+	if selector == nil {
+		return labels.Everything().String(), nil
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return "", err
+	}
+	return s.String(), nil
+}
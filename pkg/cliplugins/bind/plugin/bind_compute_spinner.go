@@ -0,0 +1,132 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// spinnerInterval is the interval the spinner is redrawn at.
+const spinnerInterval = time.Millisecond * 100
+
+// spinnerFrames are drawn in turn in front of the current phase.
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+// spinner shows the current phase of the bind on an interactive terminal, behind a spinning frame. It is a writer
+// itself, so that the progress messages are written over the spinner line rather than interleaved with it. Drawing
+// is best effort, write errors show in the progress messages.
+type spinner struct {
+	lock  sync.Mutex
+	out   io.Writer
+	phase string
+	frame int
+	shown bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// isTerminal returns whether the writer is an interactive terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// isTerminalInput returns whether the reader is an interactive terminal the user can be prompted on.
+func isTerminalInput(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+func newSpinner(out io.Writer) *spinner {
+	return &spinner{
+		out:  out,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// Start redraws the spinner at the given interval until Stop is called.
+func (s *spinner) Start(interval time.Duration) {
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.tick()
+			}
+		}
+	}()
+}
+
+// Stop stops redrawing the spinner and clears its line, leaving the final status printed after it on its own.
+func (s *spinner) Stop() {
+	close(s.stop)
+	<-s.done
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.clear()
+}
+
+// SetPhase sets the phase shown by the spinner, e.g. "waiting for readiness" is shown as "Waiting for readiness...".
+func (s *spinner) SetPhase(phase string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.phase = phase
+}
+
+// Write clears the spinner line before writing, the spinner is drawn again on the next tick.
+func (s *spinner) Write(p []byte) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.clear()
+	return s.out.Write(p)
+}
+
+// tick draws the next frame of the spinner.
+func (s *spinner) tick() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(s.phase) == 0 {
+		return
+	}
+	s.clear()
+	fmt.Fprintf(s.out, "%s %s%s...", spinnerFrames[s.frame%len(spinnerFrames)], strings.ToUpper(s.phase[:1]), s.phase[1:]) //nolint:errcheck
+	s.frame++
+	s.shown = true
+}
+
+// clear erases the spinner line, if drawn. The lock must be held.
+func (s *spinner) clear() {
+	if !s.shown {
+		return
+	}
+	fmt.Fprint(s.out, "\r\033[K") //nolint:errcheck
+	s.shown = false
+}
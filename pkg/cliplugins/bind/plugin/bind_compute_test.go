@@ -0,0 +1,2787 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgotesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	tenancyv1beta1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1beta1"
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	fakeclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned/fake"
+)
+
+func TestApplyAPIBinding(t *testing.T) {
+	tests := []struct {
+		name             string
+		existingBindings []runtime.Object
+		desiredExports   []string
+		prune            bool
+
+		wantBindings []string
+		wantCreated  []string
+		wantDeleted  []string
+	}{
+		{
+			name:           "no existing bindings",
+			desiredExports: []string{"root:compute:kubernetes"},
+			wantBindings:   []string{apiBindingName(logicalcluster.New("root:compute"), "kubernetes")},
+			wantCreated:    []string{apiBindingName(logicalcluster.New("root:compute"), "kubernetes")},
+		},
+		{
+			name: "binding named after the old location workspace path is reused",
+			existingBindings: []runtime.Object{
+				newAPIBinding(apiBindingName(logicalcluster.New("root:oldlocations"), "kubernetes"), "root:newlocations", "kubernetes"),
+			},
+			desiredExports: []string{"root:newlocations:kubernetes"},
+			wantBindings:   []string{apiBindingName(logicalcluster.New("root:oldlocations"), "kubernetes")},
+		},
+		{
+			name: "only missing bindings are created",
+			existingBindings: []runtime.Object{
+				newAPIBinding("kubernetes", "root:compute", "kubernetes"),
+			},
+			desiredExports: []string{"root:compute:kubernetes", "root:myapis:custom"},
+			wantBindings:   []string{"kubernetes", apiBindingName(logicalcluster.New("root:myapis"), "custom")},
+			wantCreated:    []string{apiBindingName(logicalcluster.New("root:myapis"), "custom")},
+		},
+		{
+			name: "prune deletes unsupported bindings created by bind compute",
+			existingBindings: []runtime.Object{
+				newAPIBinding(apiBindingName(logicalcluster.New("root:compute"), "kubernetes"), "root:compute", "kubernetes"),
+				newAPIBinding(apiBindingName(logicalcluster.New("root:myapis"), "stale"), "root:myapis", "stale"),
+				newAPIBinding("manual", "root:myapis", "manual"),
+			},
+			desiredExports: []string{"root:compute:kubernetes"},
+			prune:          true,
+			wantBindings:   []string{apiBindingName(logicalcluster.New("root:compute"), "kubernetes")},
+			wantDeleted:    []string{apiBindingName(logicalcluster.New("root:myapis"), "stale")},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			client := fakeclient.NewSimpleClientset(tt.existingBindings...)
+			opts := NewBindComputeOptions(genericclioptions.IOStreams{Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}})
+			opts.Prune = tt.prune
+
+			bindings, err := opts.applyAPIBinding(context.Background(), client, sets.NewString(tt.desiredExports...), nil)
+			require.NoError(t, err)
+
+			var names []string
+			for _, binding := range bindings {
+				names = append(names, binding.Name)
+			}
+			sort.Strings(names)
+			sort.Strings(tt.wantBindings)
+			require.Equal(t, tt.wantBindings, names)
+
+			var created, deleted []string
+			for _, action := range client.Actions() {
+				if action.GetResource().Resource != "apibindings" {
+					continue
+				}
+				switch action.GetVerb() {
+				case "create":
+					created = append(created, action.(clientgotesting.CreateAction).GetObject().(*apisv1alpha1.APIBinding).Name)
+				case "delete":
+					deleted = append(deleted, action.(clientgotesting.DeleteAction).GetName())
+				}
+			}
+			sort.Strings(created)
+			sort.Strings(tt.wantCreated)
+			require.Equal(t, tt.wantCreated, created)
+			sort.Strings(deleted)
+			sort.Strings(tt.wantDeleted)
+			require.Equal(t, tt.wantDeleted, deleted)
+		})
+	}
+}
+
+func TestApplyAPIBindingAlreadyExists(t *testing.T) {
+	existing := newAPIBinding(apiBindingName(logicalcluster.New("root:compute"), "kubernetes"), "root:compute", "kubernetes")
+	existing.Status.Phase = apisv1alpha1.APIBindingPhaseBound
+	client := fakeclient.NewSimpleClientset(existing)
+	// the binding is created concurrently after the list, so the create call fails with AlreadyExists.
+	client.PrependReactor("list", "apibindings", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+		return true, &apisv1alpha1.APIBindingList{}, nil
+	})
+
+	opts := NewBindComputeOptions(genericclioptions.IOStreams{Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}})
+	bindings, err := opts.applyAPIBinding(context.Background(), client, sets.NewString("root:compute:kubernetes"), nil)
+	require.NoError(t, err)
+	require.Len(t, bindings, 1)
+	require.NotNil(t, bindings[0])
+	require.Equal(t, apisv1alpha1.APIBindingPhaseBound, bindings[0].Status.Phase)
+
+	conflicting := newAPIBinding(apiBindingName(logicalcluster.New("root:myapis"), "custom"), "root:myapis", "other")
+	require.NoError(t, client.Tracker().Add(conflicting))
+	_, err = opts.applyAPIBinding(context.Background(), client, sets.NewString("root:myapis:custom"), nil)
+	require.ErrorContains(t, err, "already exists but does not reference apiexport root:myapis:custom")
+}
+
+func TestApplyAPIBindingContinueOnBindingError(t *testing.T) {
+	client := fakeclient.NewSimpleClientset()
+	client.PrependReactor("create", "apibindings", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+		binding := action.(clientgotesting.CreateAction).GetObject().(*apisv1alpha1.APIBinding)
+		if binding.Spec.Reference.Workspace.ExportName == "custom" {
+			return true, nil, apierrors.NewForbidden(apisv1alpha1.Resource("apibindings"), binding.Name, errors.New("no bind permission"))
+		}
+		return false, nil, nil
+	})
+	exports := sets.NewString("root:compute:kubernetes", "root:myapis:custom")
+
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	_, err := opts.applyAPIBinding(context.Background(), client, exports, nil)
+	require.ErrorContains(t, err, "no bind permission")
+	var partialErr *PartialBindError
+	require.False(t, errors.As(err, &partialErr))
+
+	streams, _, _, errOut := genericclioptions.NewTestIOStreams()
+	opts = NewBindComputeOptions(streams)
+	opts.ContinueOnBindingError = true
+	bindings, err := opts.applyAPIBinding(context.Background(), client, exports, nil)
+	require.Len(t, bindings, 1)
+	require.Equal(t, "kubernetes", bindings[0].Spec.Reference.Workspace.ExportName)
+	require.ErrorAs(t, err, &partialErr)
+	require.Equal(t, 1, partialErr.Bound)
+	require.Len(t, partialErr.Errors, 1)
+	require.Equal(t, PartialBindExitCode, partialErr.ExitCode())
+	require.Contains(t, errOut.String(), "Warning: failed to create apibinding "+apiBindingName(logicalcluster.New("root:myapis"), "custom")+" for apiexport root:myapis:custom, continuing")
+}
+
+func TestServerSideApply(t *testing.T) {
+	client := fakeclient.NewSimpleClientset()
+	// the fake client does not support apply patches, so record them and return the applied object instead.
+	var patches []clientgotesting.PatchActionImpl
+	client.PrependReactor("patch", "*", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+		patch := action.(clientgotesting.PatchActionImpl)
+		patches = append(patches, patch)
+		switch patch.GetResource().Resource {
+		case "apibindings":
+			binding := &apisv1alpha1.APIBinding{}
+			return true, binding, json.Unmarshal(patch.GetPatch(), binding)
+		default:
+			placement := &schedulingv1alpha1.Placement{}
+			return true, placement, json.Unmarshal(patch.GetPatch(), placement)
+		}
+	})
+
+	out := &bytes.Buffer{}
+	opts := NewBindComputeOptions(genericclioptions.IOStreams{Out: out, ErrOut: &bytes.Buffer{}})
+	opts.ServerSideApply = true
+	opts.PlacementName = "placement-1a2b3c4d"
+	bindings, err := opts.applyAPIBinding(context.Background(), client, sets.NewString("root:compute:kubernetes"), nil)
+	require.NoError(t, err)
+	require.Len(t, bindings, 1)
+	_, err = opts.applyPlacement(context.Background(), client)
+	require.NoError(t, err)
+
+	require.Len(t, patches, 2)
+	for _, patch := range patches {
+		require.Equal(t, types.ApplyPatchType, patch.GetPatchType())
+		require.Contains(t, string(patch.GetPatch()), `"apiVersion":`)
+	}
+	require.Equal(t, fmt.Sprintf("apibinding %s for apiexport root:compute:kubernetes applied.\nplacement placement-1a2b3c4d applied.\n", bindings[0].Name), out.String())
+}
+
+func TestVerifyExportIdentities(t *testing.T) {
+	kubernetes := &apisv1alpha1.APIExport{
+		ObjectMeta: metav1.ObjectMeta{Name: "kubernetes"},
+		Status:     apisv1alpha1.APIExportStatus{IdentityHash: "abc123"},
+	}
+	kcpClient := fakeClusterClients{"root:compute": fakeclient.NewSimpleClientset(kubernetes)}
+
+	tests := []struct {
+		name             string
+		expectIdentities []string
+		exports          []string
+		wantErr          string
+	}{
+		{name: "bare hash matches", expectIdentities: []string{"abc123"}, exports: []string{"root:compute:kubernetes"}},
+		{name: "export hash matches", expectIdentities: []string{"root:compute:kubernetes=abc123"}, exports: []string{"root:compute:kubernetes", "root:myapis:custom"}},
+		{name: "mismatch", expectIdentities: []string{"root:compute:kubernetes=def456"}, exports: []string{"root:compute:kubernetes"}, wantErr: `refusing to bind apiexport root:compute:kubernetes: its identity hash "abc123" does not match the expected "def456"`},
+		{name: "bare hash with several exports", expectIdentities: []string{"abc123"}, exports: []string{"root:compute:kubernetes", "root:myapis:custom"}, wantErr: "requires binding a single APIExport"},
+		{name: "export not bound", expectIdentities: []string{"root:myapis:custom=abc123"}, exports: []string{"root:compute:kubernetes"}, wantErr: "which is not bound"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+			opts.Kubeconfig = filepath.Join(t.TempDir(), "kubeconfig")
+			opts.ExpectIdentities = tt.expectIdentities
+			require.NoError(t, opts.Complete([]string{"root:mylocations"}))
+
+			err := opts.verifyExportIdentities(context.Background(), kcpClient, sets.NewString(tt.exports...))
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestExistingReadyBind(t *testing.T) {
+	bound := newAPIBinding(apiBindingName(logicalcluster.New("root:compute"), "kubernetes"), "root:compute", "kubernetes")
+	bound.Status.Phase = apisv1alpha1.APIBindingPhaseBound
+	placement := &schedulingv1alpha1.Placement{ObjectMeta: metav1.ObjectMeta{Name: "placement-1a2b3c4d"}}
+	conditions.MarkTrue(placement, schedulingv1alpha1.PlacementReady)
+
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.PlacementName = placement.Name
+
+	client := fakeclient.NewSimpleClientset(bound, placement)
+	bindings, existing, ready, err := opts.existingReadyBind(context.Background(), client, sets.NewString("root:compute:kubernetes"))
+	require.NoError(t, err)
+	require.True(t, ready)
+	require.Equal(t, []*apisv1alpha1.APIBinding{bound}, bindings)
+	require.Equal(t, placement.Name, existing.Name)
+	for _, action := range client.Actions() {
+		require.NotEqual(t, "create", action.GetVerb(), "nothing should be created")
+	}
+
+	_, _, ready, err = opts.existingReadyBind(context.Background(), client, sets.NewString("root:compute:kubernetes", "root:myapis:custom"))
+	require.NoError(t, err)
+	require.False(t, ready, "a missing binding should not be reported as already bound")
+
+	_, _, ready, err = opts.existingReadyBind(context.Background(), fakeclient.NewSimpleClientset(bound), sets.NewString("root:compute:kubernetes"))
+	require.NoError(t, err)
+	require.False(t, ready, "a missing placement should not be reported as already bound")
+}
+
+func TestCheckReachable(t *testing.T) {
+	require.NoError(t, checkReachable(context.Background(), fakeclient.NewSimpleClientset(), "current workspace", "https://kcp/clusters/root:org"))
+
+	forbidden := fakeclient.NewSimpleClientset()
+	forbidden.PrependReactor("list", "apibindings", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(apisv1alpha1.Resource("apibindings"), "", errors.New("not allowed"))
+	})
+	require.NoError(t, checkReachable(context.Background(), forbidden, "location workspace", "https://kcp/clusters/root:locations"), "a forbidden response still tells the workspace is reachable")
+
+	unreachable := fakeclient.NewSimpleClientset()
+	unreachable.PrependReactor("list", "apibindings", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+		return true, nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	})
+	err := checkReachable(context.Background(), unreachable, "location workspace", "https://kcp/clusters/root:locations")
+	require.ErrorContains(t, err, "cannot reach location workspace at https://kcp/clusters/root:locations: dial tcp: connection refused")
+}
+
+func TestPaginatedLists(t *testing.T) {
+	client := fakeclient.NewSimpleClientset()
+	paginate(client, "synctargets",
+		&workloadv1alpha1.SyncTargetList{
+			ListMeta: metav1.ListMeta{Continue: "page-2"},
+			Items:    []workloadv1alpha1.SyncTarget{*newSyncTarget("cluster-1", "root:compute:kubernetes")},
+		},
+		&workloadv1alpha1.SyncTargetList{
+			Items: []workloadv1alpha1.SyncTarget{*newSyncTarget("cluster-2", "root:myapis:custom")},
+		},
+	)
+	paginate(client, "apibindings",
+		&apisv1alpha1.APIBindingList{
+			ListMeta: metav1.ListMeta{Continue: "page-2"},
+			Items:    []apisv1alpha1.APIBinding{*newAPIBinding("unrelated", "root:other", "other")},
+		},
+		&apisv1alpha1.APIBindingList{
+			Items: []apisv1alpha1.APIBinding{*newAPIBinding("kubernetes", "root:compute", "kubernetes")},
+		},
+	)
+
+	opts := NewBindComputeOptions(genericclioptions.IOStreams{Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}})
+	opts.LocationWorkspace = logicalcluster.New("root:locations")
+	opts.APIExports = []string{"root:compute:kubernetes", "root:myapis:custom"}
+
+	supportedExports, err := opts.supportedAPIExports(context.Background(), client)
+	require.NoError(t, err, "exports supported by synctargets on the second page should be found")
+	require.Equal(t, []string{"root:compute:kubernetes", "root:myapis:custom"}, supportedExports.List())
+
+	bindings, err := opts.applyAPIBinding(context.Background(), client, supportedExports, nil)
+	require.NoError(t, err)
+	var names []string
+	for _, binding := range bindings {
+		names = append(names, binding.Name)
+	}
+	sort.Strings(names)
+	require.Equal(t, []string{apiBindingName(logicalcluster.New("root:myapis"), "custom"), "kubernetes"}, names,
+		"the binding on the second page should be reused")
+}
+
+// paginate makes the client return the given lists of resource one after the other, as consecutive pages.
+func paginate(client *fakeclient.Clientset, resource string, pages ...runtime.Object) {
+	client.PrependReactor("list", resource, func(action clientgotesting.Action) (bool, runtime.Object, error) {
+		page := pages[0]
+		pages = pages[1:]
+		return true, page, nil
+	})
+}
+
+func TestCheckExportDrift(t *testing.T) {
+	kubernetesName := apiBindingName(logicalcluster.New("root:compute"), "kubernetes")
+	customName := apiBindingName(logicalcluster.New("root:myapis"), "custom")
+	created := []*apisv1alpha1.APIBinding{
+		newAPIBinding(kubernetesName, "root:compute", "kubernetes"),
+		newAPIBinding(customName, "root:myapis", "custom"),
+	}
+	// the reference of the custom binding was rewritten server-side.
+	client := fakeclient.NewSimpleClientset(
+		newAPIBinding(kubernetesName, "root:compute", "kubernetes"),
+		newAPIBinding(customName, "root:myapis", "custom-v2"),
+	)
+
+	streams, _, _, errOut := genericclioptions.NewTestIOStreams()
+	opts := NewBindComputeOptions(streams)
+	bindings, err := opts.checkExportDrift(context.Background(), client, sets.NewString("root:compute:kubernetes", "root:myapis:custom"), created)
+	require.NoError(t, err)
+	require.Len(t, bindings, 2)
+	require.Equal(t, "custom-v2", bindings[1].Spec.Reference.Workspace.ExportName, "the bindings should be re-read")
+	require.Equal(t, fmt.Sprintf(`Warning: apibinding %s references apiexport root:myapis:custom-v2, which was not requested
+Warning: apiexport root:myapis:custom was requested, but no apibinding references it
+`, customName), errOut.String())
+
+	errOut.Reset()
+	_, err = opts.checkExportDrift(context.Background(), client, sets.NewString("root:compute:kubernetes"), created[:1])
+	require.NoError(t, err)
+	require.Empty(t, errOut.String())
+}
+
+func TestWaitForExistingBind(t *testing.T) {
+	bound := newAPIBinding(apiBindingName(logicalcluster.New("root:compute"), "kubernetes"), "root:compute", "kubernetes")
+	bound.Status.Phase = apisv1alpha1.APIBindingPhaseBound
+	ready := &schedulingv1alpha1.Placement{ObjectMeta: metav1.ObjectMeta{Name: "placement-1a2b3c4d"}}
+	conditions.MarkTrue(ready, schedulingv1alpha1.PlacementReady)
+	notReady := &schedulingv1alpha1.Placement{ObjectMeta: metav1.ObjectMeta{Name: "placement-1a2b3c4d"}}
+	exports := sets.NewString("root:compute:kubernetes")
+
+	newOptions := func() (*BindComputeOptions, *bytes.Buffer) {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		opts := NewBindComputeOptions(streams)
+		opts.PlacementName = "placement-1a2b3c4d"
+		opts.Table = false
+		opts.PollInterval = time.Millisecond
+		opts.BindWaitTimeout = time.Millisecond * 50
+		return opts, out
+	}
+
+	opts, out := newOptions()
+	bindings, placement, err := opts.waitForExistingBind(context.Background(), fakeclient.NewSimpleClientset(bound, ready), exports, time.Now())
+	require.NoError(t, err)
+	require.Len(t, bindings, 1)
+	require.Equal(t, "placement-1a2b3c4d", placement.Name)
+	require.Contains(t, out.String(), "bound 1 APIExport(s) with placement placement-1a2b3c4d")
+
+	opts, out = newOptions()
+	opts.Table = true
+	_, _, err = opts.waitForExistingBind(context.Background(), fakeclient.NewSimpleClientset(bound, ready), exports, time.Now())
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "KIND")
+	require.Contains(t, out.String(), "bound 1 APIExport(s) with placement placement-1a2b3c4d")
+
+	opts, out = newOptions()
+	opts.Table = true
+	opts.Output = "yaml"
+	_, _, err = opts.waitForExistingBind(context.Background(), fakeclient.NewSimpleClientset(bound, ready), exports, time.Now())
+	require.NoError(t, err)
+	require.NotContains(t, out.String(), "KIND", "the table is not printed with an output meant to be parsed")
+
+	opts, _ = newOptions()
+	client := fakeclient.NewSimpleClientset(bound, notReady)
+	_, _, err = opts.waitForExistingBind(context.Background(), client, exports, time.Now())
+	require.ErrorIs(t, err, ErrWaitTimeout)
+	require.ErrorContains(t, err, "bind compute is not ready placement-1a2b3c4d")
+	for _, action := range client.Actions() {
+		require.Contains(t, []string{"get", "list"}, action.GetVerb(), "nothing should be created")
+	}
+
+	opts, _ = newOptions()
+	_, _, err = opts.waitForExistingBind(context.Background(), fakeclient.NewSimpleClientset(bound), exports, time.Now())
+	require.ErrorContains(t, err, "placement placement-1a2b3c4d or the APIBindings of the APIExports do not exist")
+}
+
+func newSyncTarget(name string, exports ...string) *workloadv1alpha1.SyncTarget {
+	syncTarget := &workloadv1alpha1.SyncTarget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+	}
+	for _, export := range exports {
+		path, exportName := logicalcluster.New(export).Split()
+		syncTarget.Spec.SupportedAPIExports = append(syncTarget.Spec.SupportedAPIExports, apisv1alpha1.ExportReference{
+			Workspace: &apisv1alpha1.WorkspaceExportReference{
+				Path:       path.String(),
+				ExportName: exportName,
+			},
+		})
+	}
+	return syncTarget
+}
+
+func newAPIBinding(name, path, exportName string) *apisv1alpha1.APIBinding {
+	return &apisv1alpha1.APIBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: apisv1alpha1.APIBindingSpec{
+			Reference: apisv1alpha1.ExportReference{
+				Workspace: &apisv1alpha1.WorkspaceExportReference{
+					Path:       path,
+					ExportName: exportName,
+				},
+			},
+		},
+	}
+}
+
+func TestObjectYAML(t *testing.T) {
+	manifest, err := objectYAML(newAPIBinding("kubernetes", "root:compute", "kubernetes"))
+	require.NoError(t, err)
+	require.Equal(t, `apiVersion: apis.kcp.dev/v1alpha1
+kind: APIBinding
+metadata:
+  name: kubernetes
+spec:
+  reference:
+    workspace:
+      exportName: kubernetes
+      path: root:compute
+`, string(manifest))
+}
+
+func TestWriteObjectFiles(t *testing.T) {
+	binding := newAPIBinding("kubernetes", "root:compute", "kubernetes")
+	binding.UID = "uid"
+	binding.ResourceVersion = "42"
+	binding.Generation = 1
+	binding.Annotations = map[string]string{logicalcluster.AnnotationKey: "root:org"}
+	binding.Status.Phase = apisv1alpha1.APIBindingPhaseBound
+
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.ObjectsDir = filepath.Join(t.TempDir(), "manifests")
+	require.NoError(t, opts.writeObjectFiles([]runtime.Object{binding}))
+
+	manifest, err := os.ReadFile(filepath.Join(opts.ObjectsDir, "apibinding-kubernetes.yaml"))
+	require.NoError(t, err)
+	require.Equal(t, `apiVersion: apis.kcp.dev/v1alpha1
+kind: APIBinding
+metadata:
+  name: kubernetes
+spec:
+  reference:
+    workspace:
+      exportName: kubernetes
+      path: root:compute
+`, string(manifest))
+}
+
+func TestSupportedAPIExportsWithoutSyncTargets(t *testing.T) {
+	for _, apiExports := range [][]string{nil, {"root:myapis:custom"}} {
+		opts := NewBindComputeOptions(genericclioptions.IOStreams{Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}})
+		opts.LocationWorkspace = logicalcluster.New("root:locations")
+		opts.APIExports = apiExports
+
+		_, err := opts.supportedAPIExports(context.Background(), fakeclient.NewSimpleClientset())
+		var noSyncTargetsErr *NoSyncTargetsError
+		require.ErrorAs(t, err, &noSyncTargetsErr)
+		require.Equal(t, logicalcluster.New("root:locations"), noSyncTargetsErr.LocationWorkspace)
+	}
+}
+
+func TestValidateSelectorOperators(t *testing.T) {
+	tests := []struct {
+		name        string
+		requirement metav1.LabelSelectorRequirement
+		wantErr     string
+	}{
+		{
+			name:        "in with values",
+			requirement: metav1.LabelSelectorRequirement{Key: "region", Operator: metav1.LabelSelectorOpIn, Values: []string{"us-east1"}},
+		},
+		{
+			name:        "exists without values",
+			requirement: metav1.LabelSelectorRequirement{Key: "region", Operator: metav1.LabelSelectorOpExists},
+		},
+		{
+			name:        "unsupported operator",
+			requirement: metav1.LabelSelectorRequirement{Key: "cpu", Operator: "Gt", Values: []string{"4"}},
+			wantErr:     `operator "Gt" on key "cpu" is not supported`,
+		},
+		{
+			name:        "notin without values",
+			requirement: metav1.LabelSelectorRequirement{Key: "region", Operator: metav1.LabelSelectorOpNotIn},
+			wantErr:     `operator NotIn on key "region" requires at least one value`,
+		},
+		{
+			name:        "does not exist with values",
+			requirement: metav1.LabelSelectorRequirement{Key: "region", Operator: metav1.LabelSelectorOpDoesNotExist, Values: []string{"us-east1"}},
+			wantErr:     `operator DoesNotExist on key "region" does not take values`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSelectorOperators(&metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{tt.requirement}})
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestAcceptPermissionClaims(t *testing.T) {
+	configmaps := apisv1alpha1.PermissionClaim{GroupResource: apisv1alpha1.GroupResource{Resource: "configmaps"}, All: true}
+	widgets := apisv1alpha1.PermissionClaim{GroupResource: apisv1alpha1.GroupResource{Group: "example.com", Resource: "widgets"}, All: true, IdentityHash: "abc"}
+	offered := map[string][]apisv1alpha1.PermissionClaim{
+		"root:compute:kubernetes": {configmaps},
+		"root:myapis:widgets":     {configmaps, widgets},
+	}
+
+	tests := []struct {
+		name      string
+		requested []string
+		accepted  map[string][]string
+		wantErr   bool
+	}{
+		{
+			name:      "all",
+			requested: []string{"all"},
+			accepted: map[string][]string{
+				"root:compute:kubernetes": {"configmaps"},
+				"root:myapis:widgets":     {"configmaps", "widgets.example.com"},
+			},
+		},
+		{
+			name:      "list",
+			requested: []string{"widgets.example.com"},
+			accepted: map[string][]string{
+				"root:myapis:widgets": {"widgets.example.com"},
+			},
+		},
+		{
+			name:      "not offered",
+			requested: []string{"widgets.example.com", "secrets"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := acceptPermissionClaims(offered, tt.requested)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			accepted := map[string][]string{}
+			for export, exportClaims := range claims {
+				for _, claim := range exportClaims {
+					require.Equal(t, apisv1alpha1.ClaimAccepted, claim.State)
+					accepted[export] = append(accepted[export], permissionClaimKey(claim.PermissionClaim))
+				}
+			}
+			require.Equal(t, tt.accepted, accepted)
+		})
+	}
+}
+
+func TestPendingPermissionClaims(t *testing.T) {
+	configmaps := apisv1alpha1.PermissionClaim{GroupResource: apisv1alpha1.GroupResource{Resource: "configmaps"}, All: true}
+	secrets := apisv1alpha1.PermissionClaim{GroupResource: apisv1alpha1.GroupResource{Resource: "secrets"}, All: true}
+	widgets := apisv1alpha1.PermissionClaim{GroupResource: apisv1alpha1.GroupResource{Group: "example.com", Resource: "widgets"}, All: true}
+
+	binding := newAPIBinding("widgets", "root:myapis", "widgets")
+	binding.Spec.PermissionClaims = []apisv1alpha1.AcceptablePermissionClaim{
+		{PermissionClaim: configmaps, State: apisv1alpha1.ClaimAccepted},
+		{PermissionClaim: secrets, State: apisv1alpha1.ClaimRejected},
+	}
+	binding.Status.ExportPermissionClaims = []apisv1alpha1.PermissionClaim{configmaps, secrets, widgets}
+
+	require.Equal(t, []string{"widgets.example.com"}, pendingPermissionClaims(binding))
+
+	var out bytes.Buffer
+	require.NoError(t, printPendingPermissionClaims(&out, binding))
+	require.Contains(t, out.String(), "apibinding widgets is waiting for acceptance of claims: [widgets.example.com]")
+}
+
+func TestCompleteLocationWorkspace(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	require.NoError(t, clientcmd.WriteToFile(clientcmdapi.Config{
+		Clusters:       map[string]*clientcmdapi.Cluster{"workspace": {Server: "https://test/clusters/root:org"}},
+		Contexts:       map[string]*clientcmdapi.Context{"workspace": {Cluster: "workspace"}},
+		CurrentContext: "workspace",
+	}, kubeconfig))
+
+	tests := []struct {
+		name     string
+		arg      string
+		expected string
+		wantErr  bool
+	}{
+		{name: "absolute", arg: "root:mylocations", expected: "root:mylocations"},
+		{name: "root", arg: "root", expected: "root"},
+		{name: "relative", arg: "team:locations", expected: "root:org:team:locations"},
+		{name: "relative child", arg: "locations", expected: "root:org:locations"},
+		{name: "invalid", arg: "Locations", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+			opts.Kubeconfig = kubeconfig
+			err := opts.Complete([]string{tt.arg})
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, opts.LocationWorkspace.String())
+		})
+	}
+}
+
+func TestPollUntilReady(t *testing.T) {
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.PollInterval = time.Millisecond
+	opts.PollFactor = 2
+	opts.PollMaxInterval = time.Millisecond * 4
+	opts.BindWaitTimeout = time.Second * 10
+
+	checks := 0
+	err := opts.pollUntilReady(context.Background(), func(ctx context.Context) (bool, error) {
+		checks++
+		return checks == 6, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 6, checks)
+
+	opts.BindWaitTimeout = time.Millisecond * 20
+	err = opts.pollUntilReady(context.Background(), func(ctx context.Context) (bool, error) {
+		return false, nil
+	})
+	require.ErrorIs(t, err, wait.ErrWaitTimeout)
+	require.ErrorIs(t, err, ErrWaitTimeout)
+	require.NotErrorIs(t, err, ErrWaitInterrupted)
+
+	opts.BindWaitTimeout = time.Second * 10
+	ctx, cancel := context.WithCancel(context.Background())
+	err = opts.pollUntilReady(ctx, func(ctx context.Context) (bool, error) {
+		cancel()
+		return false, nil
+	})
+	require.ErrorIs(t, err, ErrWaitInterrupted)
+	require.ErrorIs(t, err, context.Canceled)
+	require.NotErrorIs(t, err, ErrWaitTimeout)
+	require.False(t, IsRetryableError(err))
+}
+
+func TestSupportedAPIExportsKubernetesVersion(t *testing.T) {
+	tests := []struct {
+		name              string
+		kubernetesVersion string
+		syncTargetExports []string
+		wantExports       []string
+		wantErr           bool
+	}{
+		{
+			name:              "default",
+			syncTargetExports: []string{"root:compute:kubernetes", "root:compute:kubernetes-v1-24"},
+			wantExports:       []string{"root:compute:kubernetes"},
+		},
+		{
+			name:              "version",
+			kubernetesVersion: "v1-24",
+			syncTargetExports: []string{"root:compute:kubernetes", "root:compute:kubernetes-v1-24", "root:locations:kubernetes-v1-24"},
+			wantExports:       []string{"root:compute:kubernetes-v1-24", "root:locations:kubernetes-v1-24"},
+		},
+		{
+			name:              "unsupported version",
+			kubernetesVersion: "v1-25",
+			syncTargetExports: []string{"root:compute:kubernetes", "root:compute:kubernetes-v1-24"},
+			wantErr:           true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+			opts.LocationWorkspace = logicalcluster.New("root:locations")
+			opts.KubernetesVersion = tt.kubernetesVersion
+
+			exports, err := opts.supportedAPIExports(context.Background(), fakeclient.NewSimpleClientset(newSyncTarget("cluster-1", tt.syncTargetExports...)))
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantExports, exports.List())
+		})
+	}
+}
+
+func TestSupportedAPIExportsExclude(t *testing.T) {
+	tests := []struct {
+		name                  string
+		excludeAPIExports     []string
+		ignoreMissingExcludes bool
+		wantExports           []string
+		wantErrorContains     string
+	}{
+		{
+			name:              "excluded from refresh",
+			excludeAPIExports: []string{"root:myapis:custom"},
+			wantExports:       []string{"root:compute:kubernetes", "root:myapis:other"},
+		},
+		{
+			name:              "missing exclude",
+			excludeAPIExports: []string{"root:myapis:custom", "root:myapis:unknown"},
+			wantErrorContains: "the following excluded APIExports are not among the APIExports to bind: root:myapis:unknown",
+		},
+		{
+			name:                  "ignored missing exclude",
+			excludeAPIExports:     []string{"root:myapis:custom", "root:myapis:unknown"},
+			ignoreMissingExcludes: true,
+			wantExports:           []string{"root:compute:kubernetes", "root:myapis:other"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+			opts.LocationWorkspace = logicalcluster.New("root:locations")
+			opts.Refresh = true
+			opts.ExcludeAPIExports = tt.excludeAPIExports
+			opts.IgnoreMissingExcludes = tt.ignoreMissingExcludes
+
+			client := fakeclient.NewSimpleClientset(newSyncTarget("cluster-1", "root:compute:kubernetes", "root:myapis:custom", "root:myapis:other"))
+			exports, err := opts.supportedAPIExports(context.Background(), client)
+			if tt.wantErrorContains != "" {
+				require.ErrorContains(t, err, tt.wantErrorContains)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantExports, exports.List())
+		})
+	}
+}
+
+func TestSupportedAPIExportsFromSyncTarget(t *testing.T) {
+	client := fakeclient.NewSimpleClientset(
+		newSyncTarget("cluster-1", "root:compute:kubernetes"),
+		newSyncTarget("cluster-2", "root:compute:kubernetes", "root:myapis:custom"),
+	)
+
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.LocationWorkspace = logicalcluster.New("root:locations")
+	opts.Refresh = true
+	opts.FromSyncTarget = "cluster-1"
+	exports, err := opts.supportedAPIExports(context.Background(), client)
+	require.NoError(t, err)
+	require.Equal(t, []string{"root:compute:kubernetes"}, exports.List())
+
+	opts.APIExports = []string{"root:myapis:custom"}
+	_, err = opts.supportedAPIExports(context.Background(), client)
+	require.ErrorContains(t, err, "the following APIExports are not supported by the synctargets in workspace root:locations: root:myapis:custom")
+
+	opts.FromSyncTarget = "cluster-3"
+	_, err = opts.supportedAPIExports(context.Background(), client)
+	require.EqualError(t, err, "synctarget cluster-3 not found in workspace root:locations")
+}
+
+func TestCheckAPIGroups(t *testing.T) {
+	client := fakeclient.NewSimpleClientset()
+	client.Resources = []*metav1.APIResourceList{
+		{GroupVersion: apisv1alpha1.SchemeGroupVersion.String()},
+		{GroupVersion: workloadv1alpha1.SchemeGroupVersion.String()},
+	}
+	require.EqualError(t, checkAPIGroups(client.Discovery(), userWorkspaceAPIs), "this kcp server does not support Placements (scheduling.kcp.dev/v1alpha1); upgrade kcp or the plugin")
+
+	client.Resources = append(client.Resources, &metav1.APIResourceList{GroupVersion: schedulingv1alpha1.SchemeGroupVersion.String()})
+	require.NoError(t, checkAPIGroups(client.Discovery(), userWorkspaceAPIs))
+	require.NoError(t, checkAPIGroups(client.Discovery(), locationWorkspaceAPIs))
+}
+
+func TestLocationResource(t *testing.T) {
+	tests := []struct {
+		locationResource  string
+		wantErrorContains string
+	}{
+		{locationResource: "synctargets.v1alpha1.workload.kcp.dev"},
+		{locationResource: "SyncTargets.v1alpha1.workload.kcp.dev", wantErrorContains: `location resource "SyncTargets" must be the plural lowercase resource name`},
+		{locationResource: "synctargets", wantErrorContains: `location resource "synctargets" must be in the format <resource>.<version>.<group>`},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.locationResource, func(t *testing.T) {
+			opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+			opts.Kubeconfig = filepath.Join(t.TempDir(), "kubeconfig")
+			opts.LocationResource = tt.locationResource
+			err := opts.Complete([]string{"root:mylocations"})
+			if err == nil {
+				err = opts.Validate()
+			}
+			if tt.wantErrorContains != "" {
+				require.ErrorContains(t, err, tt.wantErrorContains)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, defaultLocationResource, opts.placementSpec().LocationResource)
+		})
+	}
+
+	client := fakeclient.NewSimpleClientset()
+	client.Resources = []*metav1.APIResourceList{{
+		GroupVersion: workloadv1alpha1.SchemeGroupVersion.String(),
+		APIResources: []metav1.APIResource{
+			{Name: "synctargets", SingularName: "synctarget", Kind: "SyncTarget"},
+			{Name: "synctargets/status", Kind: "SyncTarget"},
+			{Name: "synctargetheartbeats", Kind: "SyncTargetHeartbeat"},
+			{Name: "criteria", SingularName: "criterion", Kind: "Criterion"},
+			{Name: "statuses", SingularName: "status", Kind: "Status"},
+		},
+	}}
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.LocationWorkspace = logicalcluster.New("root:mylocations")
+	require.NoError(t, opts.checkLocationResource(client.Discovery()))
+
+	opts.locationResource.Resource = "clusters"
+	require.EqualError(t, opts.checkLocationResource(client.Discovery()),
+		"location resource clusters.v1alpha1.workload.kcp.dev is not served in workspace root:mylocations, workload.kcp.dev/v1alpha1 serves: criteria, statuses, synctargetheartbeats, synctargets")
+
+	opts.locationResource.Resource = "synctarget"
+	require.EqualError(t, opts.checkLocationResource(client.Discovery()),
+		"location resource synctarget.v1alpha1.workload.kcp.dev is the singular name of synctargets, use the plural resource name synctargets instead")
+
+	// a plural name not ending in s, and a singular name ending in s, are told apart by discovery.
+	opts.locationResource.Resource = "criteria"
+	require.NoError(t, opts.checkLocationResource(client.Discovery()))
+	opts.locationResource.Resource = "status"
+	require.ErrorContains(t, opts.checkLocationResource(client.Discovery()), "use the plural resource name statuses")
+}
+
+func TestSupportedAPIExportsFromWorkspace(t *testing.T) {
+	workspaceClient := fakeclient.NewSimpleClientset(
+		&apisv1alpha1.APIExport{ObjectMeta: metav1.ObjectMeta{Name: "databases"}},
+		&apisv1alpha1.APIExport{ObjectMeta: metav1.ObjectMeta{Name: "queues"}},
+	)
+	workspaceExports, err := listWorkspaceAPIExports(context.Background(), workspaceClient, logicalcluster.New("root:shared"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"root:shared:databases", "root:shared:queues"}, workspaceExports.List())
+
+	streams, _, _, errOut := genericclioptions.NewTestIOStreams()
+	opts := NewBindComputeOptions(streams)
+	opts.LocationWorkspace = logicalcluster.New("root:locations")
+	opts.apiExportsFromWorkspace = logicalcluster.New("root:shared")
+	opts.workspaceAPIExports = workspaceExports
+
+	exports, err := opts.supportedAPIExports(context.Background(), fakeclient.NewSimpleClientset(newSyncTarget("cluster-1", "root:compute:kubernetes", "root:shared:databases")))
+	require.NoError(t, err)
+	require.Equal(t, []string{"root:compute:kubernetes", "root:shared:databases"}, exports.List())
+	require.Contains(t, errOut.String(), "not supported by any synctarget in workspace root:locations: root:shared:queues")
+}
+
+func TestNormalizeSelectorList(t *testing.T) {
+	require.Equal(t, []string{"region=us-east1", "cloud=aws", "tier in (gold,silver)"},
+		normalizeSelectorList([]string{"region=us-east1\n\n  cloud=aws  \n", "tier in (gold,silver)", " "}))
+	require.Equal(t, []string{labels.Everything().String()}, normalizeSelectorList([]string{"\n", ""}))
+}
+
+func TestCompletePlacements(t *testing.T) {
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.Kubeconfig = filepath.Join(t.TempDir(), "kubeconfig")
+	opts.NamespaceSelectorString = "kcp.dev/workload=true"
+	opts.Placements = []string{
+		"name=east,location-selectors=region=us-east1,tier in (gold,silver),location-selectors=cloud=aws",
+		"location-selectors=region=eu-west1,namespace-selector=team=a,env=prod",
+	}
+	require.NoError(t, opts.Complete([]string{"root:mylocations"}))
+	require.NoError(t, opts.Validate())
+
+	placementOpts := opts.placementOptions()
+	require.Len(t, placementOpts, 2)
+	require.Equal(t, "east", placementOpts[0].PlacementName)
+	require.Equal(t, []string{"region=us-east1,tier in (gold,silver)", "cloud=aws"}, placementOpts[0].LocationSelectorsStrings)
+	require.Equal(t, "kcp.dev/workload=true", metav1.FormatLabelSelector(placementOpts[0].placementSpec().NamespaceSelector))
+	require.Len(t, placementOpts[0].placementSpec().LocationSelectors, 2)
+	require.Equal(t, placementName("team=a,env=prod", []string{"region=eu-west1"}, logicalcluster.New("root:mylocations")), placementOpts[1].PlacementName)
+	require.Equal(t, "env=prod,team=a", metav1.FormatLabelSelector(placementOpts[1].placementSpec().NamespaceSelector))
+	require.Equal(t, "root:mylocations", placementOpts[1].placementSpec().LocationWorkspace)
+
+	opts = NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.Kubeconfig = filepath.Join(t.TempDir(), "kubeconfig")
+	opts.Placements = []string{"region=us-east1", "name=west,location-selectors=region in (us-west1"}
+	err := opts.Complete([]string{"root:mylocations"})
+	require.ErrorContains(t, err, "must start with one of name=, location-selectors= or namespace-selector=")
+	require.ErrorContains(t, err, `invalid placement "name=west,location-selectors=region in (us-west1"`)
+}
+
+func TestCompleteLocationMatchLabels(t *testing.T) {
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.Kubeconfig = filepath.Join(t.TempDir(), "kubeconfig")
+	opts.LocationMatchLabels = []string{"tier=gold,region=us", "region=eu"}
+	require.NoError(t, opts.Complete([]string{"root:mylocations"}))
+	require.Equal(t, []string{"region=us,tier=gold", "region=eu"}, opts.LocationSelectorsStrings)
+	require.Len(t, opts.locationSelectors, 2)
+	require.Equal(t, map[string]string{"region": "us", "tier": "gold"}, opts.locationSelectors[0].MatchLabels)
+	require.Equal(t, map[string]string{"region": "eu"}, opts.locationSelectors[1].MatchLabels)
+
+	opts = NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.Kubeconfig = filepath.Join(t.TempDir(), "kubeconfig")
+	opts.LocationSelectorsStrings = []string{"cloud in (aws,gcp)"}
+	opts.LocationMatchLabels = []string{"region=us"}
+	require.NoError(t, opts.Complete([]string{"root:mylocations"}))
+	require.Equal(t, []string{"cloud in (aws,gcp)", "region=us"}, opts.LocationSelectorsStrings)
+
+	opts = NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.Kubeconfig = filepath.Join(t.TempDir(), "kubeconfig")
+	opts.LocationMatchLabels = []string{"region in (us)"}
+	require.ErrorContains(t, opts.Complete([]string{"root:mylocations"}), `location match labels "region in (us)" must be key=value pairs`)
+}
+
+func TestCompleteNamespaceMatchExpressions(t *testing.T) {
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.Kubeconfig = filepath.Join(t.TempDir(), "kubeconfig")
+	opts.NamespaceSelectorString = "team=a"
+	opts.NamespaceMatchExpressions = []string{"env,in,prod,staging", "legacy,DoesNotExist"}
+	require.NoError(t, opts.Complete([]string{"root:mylocations"}))
+	require.Equal(t, "env in (prod,staging),!legacy,team=a", metav1.FormatLabelSelector(opts.namespaceSelector))
+
+	withoutExpressions := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	withoutExpressions.Kubeconfig = opts.Kubeconfig
+	withoutExpressions.NamespaceSelectorString = "team=a"
+	require.NoError(t, withoutExpressions.Complete([]string{"root:mylocations"}))
+	require.NotEqual(t, withoutExpressions.PlacementName, opts.PlacementName)
+
+	tests := map[string]string{
+		"env":                 "must be in the format key,operator[,value...]",
+		"env,Exists,prod":     "operator Exists on key \"env\" does not take values",
+		"env,In":              "operator In on key \"env\" requires at least one value",
+		"env,Like,prod":       "operator \"Like\" on key \"env\" is not supported",
+		"env,In,not a value!": "invalid value \"not a value!\"",
+		"bad key!,Exists":     "invalid key \"bad key!\"",
+	}
+	for expression, wantErr := range tests {
+		opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+		opts.Kubeconfig = filepath.Join(t.TempDir(), "kubeconfig")
+		opts.NamespaceMatchExpressions = []string{expression}
+		require.ErrorContains(t, opts.Complete([]string{"root:mylocations"}), wantErr, expression)
+	}
+}
+
+func TestCompleteSelectorErrors(t *testing.T) {
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.Kubeconfig = filepath.Join(t.TempDir(), "kubeconfig")
+	opts.NamespaceSelectorString = "env in (prod"
+	opts.LocationSelectorsStrings = []string{"region=us-east1", "cloud in (aws", "!zone=a"}
+
+	err := opts.Complete([]string{"root:mylocations"})
+	var aggregate utilerrors.Aggregate
+	require.ErrorAs(t, err, &aggregate)
+	require.Len(t, aggregate.Errors(), 3)
+}
+
+func TestPrintNameVars(t *testing.T) {
+	placement := &schedulingv1alpha1.Placement{ObjectMeta: metav1.ObjectMeta{Name: "placement-1a2b3c4d"}}
+	bindings := []*apisv1alpha1.APIBinding{
+		newAPIBinding("kubernetes-abc", "root:compute", "kubernetes"),
+		newAPIBinding("custom-def", "root:myapis", "custom"),
+	}
+
+	var out bytes.Buffer
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	require.NoError(t, opts.printNameVars(&out, placement, bindings))
+	require.Equal(t, "PLACEMENT_NAME=placement-1a2b3c4d\nBINDING_NAMES=custom-def,kubernetes-abc\n", out.String())
+}
+
+func TestValidatePlacementName(t *testing.T) {
+	tests := []struct {
+		name          string
+		placementName string
+		wantErr       bool
+	}{
+		{name: "generated", placementName: "placement-1a2b3c4d"},
+		{name: "max length", placementName: strings.Repeat("a", 253)},
+		{name: "too long", placementName: strings.Repeat("a", 254), wantErr: true},
+		{name: "uppercase", placementName: "Placement", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+			opts.PlacementName = tt.placementName
+			err := opts.Validate()
+			if tt.wantErr {
+				require.ErrorContains(t, err, "invalid placement name")
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestBindReady(t *testing.T) {
+	bound := newAPIBinding("bound", "root:compute", "kubernetes")
+	bound.Status.Phase = apisv1alpha1.APIBindingPhaseBound
+	binding := newAPIBinding("binding", "root:myapis", "custom")
+	binding.Status.Phase = apisv1alpha1.APIBindingPhaseBinding
+
+	readyPlacement := &schedulingv1alpha1.Placement{}
+	conditions.MarkTrue(readyPlacement, schedulingv1alpha1.PlacementReady)
+	pendingPlacement := &schedulingv1alpha1.Placement{}
+	scheduledPlacement := readyPlacement.DeepCopy()
+	conditions.MarkTrue(scheduledPlacement, "WorkloadScheduled")
+
+	tests := []struct {
+		name               string
+		waitBindingsOnly   bool
+		waitPlacementOnly  bool
+		requiredConditions []string
+		bindings           []*apisv1alpha1.APIBinding
+		placement          *schedulingv1alpha1.Placement
+		ready              bool
+	}{
+		{name: "all ready", bindings: []*apisv1alpha1.APIBinding{bound}, placement: readyPlacement, ready: true},
+		{name: "binding not bound", bindings: []*apisv1alpha1.APIBinding{bound, binding}, placement: readyPlacement},
+		{name: "placement not ready", bindings: []*apisv1alpha1.APIBinding{bound}, placement: pendingPlacement},
+		{name: "bindings only", waitBindingsOnly: true, bindings: []*apisv1alpha1.APIBinding{bound}, placement: pendingPlacement, ready: true},
+		{name: "bindings only, not bound", waitBindingsOnly: true, bindings: []*apisv1alpha1.APIBinding{binding}, placement: readyPlacement},
+		{name: "placement only", waitPlacementOnly: true, bindings: []*apisv1alpha1.APIBinding{binding}, placement: readyPlacement, ready: true},
+		{name: "placement only, not ready", waitPlacementOnly: true, bindings: []*apisv1alpha1.APIBinding{bound}, placement: pendingPlacement},
+		{name: "required condition true", requiredConditions: []string{"WorkloadScheduled"}, bindings: []*apisv1alpha1.APIBinding{bound}, placement: scheduledPlacement, ready: true},
+		{name: "required condition missing", requiredConditions: []string{"WorkloadScheduled"}, bindings: []*apisv1alpha1.APIBinding{bound}, placement: readyPlacement},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+			opts.WaitBindingsOnly = tt.waitBindingsOnly
+			opts.WaitPlacementOnly = tt.waitPlacementOnly
+			opts.RequiredConditions = tt.requiredConditions
+			require.Equal(t, tt.ready, opts.bindReady(tt.bindings, tt.placement))
+		})
+	}
+}
+
+func TestTerminalFailure(t *testing.T) {
+	binding := newAPIBinding("binding", "root:myapis", "custom")
+	binding.Status.Phase = apisv1alpha1.APIBindingPhaseBinding
+	missingExport := binding.DeepCopy()
+	conditions.MarkFalse(missingExport, apisv1alpha1.APIExportValid, apisv1alpha1.APIExportNotFoundReason, conditionsv1alpha1.ConditionSeverityError, "APIExport custom not found")
+	conflicting := binding.DeepCopy()
+	conditions.MarkFalse(conflicting, apisv1alpha1.BindingUpToDate, apisv1alpha1.NamingConflictsReason, conditionsv1alpha1.ConditionSeverityError, "widgets.example.com is already bound")
+	waiting := binding.DeepCopy()
+	conditions.MarkFalse(waiting, apisv1alpha1.InitialBindingCompleted, apisv1alpha1.WaitingForEstablishedReason, conditionsv1alpha1.ConditionSeverityInfo, "waiting for CRDs")
+
+	pendingPlacement := &schedulingv1alpha1.Placement{ObjectMeta: metav1.ObjectMeta{Name: "placement-1"}}
+	notFoundPlacement := pendingPlacement.DeepCopy()
+	conditions.MarkFalse(notFoundPlacement, schedulingv1alpha1.PlacementReady, schedulingv1alpha1.LocationNotFoundReason, conditionsv1alpha1.ConditionSeverityError, "no location")
+	noMatchPlacement := pendingPlacement.DeepCopy()
+	conditions.MarkFalse(noMatchPlacement, schedulingv1alpha1.PlacementReady, schedulingv1alpha1.LocationNotMatchReason, conditionsv1alpha1.ConditionSeverityError, "no location matches env=prod")
+
+	tests := []struct {
+		name              string
+		waitBindingsOnly  bool
+		waitPlacementOnly bool
+		bindings          []*apisv1alpha1.APIBinding
+		placement         *schedulingv1alpha1.Placement
+		wantErr           string
+	}{
+		{name: "pending", bindings: []*apisv1alpha1.APIBinding{binding, waiting}, placement: notFoundPlacement},
+		{name: "missing export", bindings: []*apisv1alpha1.APIBinding{missingExport}, placement: pendingPlacement,
+			wantErr: "apibinding binding failed: APIExportValid is False with reason APIExportNotFound: APIExport custom not found"},
+		{name: "naming conflict", bindings: []*apisv1alpha1.APIBinding{conflicting}, placement: pendingPlacement,
+			wantErr: "apibinding binding failed: BindingUpToDate is False with reason NamingConflicts: widgets.example.com is already bound"},
+		{name: "no matching location", bindings: []*apisv1alpha1.APIBinding{binding}, placement: noMatchPlacement,
+			wantErr: "placement placement-1 failed: Ready is False with reason LocationNoMatch: no location matches env=prod"},
+		{name: "bindings only", waitBindingsOnly: true, bindings: []*apisv1alpha1.APIBinding{binding}, placement: noMatchPlacement},
+		{name: "placement only", waitPlacementOnly: true, bindings: []*apisv1alpha1.APIBinding{missingExport}, placement: pendingPlacement},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+			opts.WaitBindingsOnly = tt.waitBindingsOnly
+			opts.WaitPlacementOnly = tt.waitPlacementOnly
+			err := opts.terminalFailure(tt.bindings, tt.placement)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{name: "wait timeout", err: fmt.Errorf("bind compute is not ready placement-1: %w", wait.ErrWaitTimeout)},
+		{name: "deadline exceeded", err: fmt.Errorf("deadline of 1m0s exceeded while waiting: %w", context.DeadlineExceeded)},
+		{name: "network error", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, retryable: true},
+		{name: "service unavailable", err: apierrors.NewServiceUnavailable("unavailable"), retryable: true},
+		{name: "too many requests", err: apierrors.NewTooManyRequests("slow down", 1), retryable: true},
+		{name: "aggregate with transient error", err: utilerrors.NewAggregate([]error{errors.New("bad"), apierrors.NewInternalError(errors.New("boom"))}), retryable: true},
+		{name: "forbidden", err: apierrors.NewForbidden(schema.GroupResource{Resource: "placements"}, "placement-1", errors.New("denied"))},
+		{name: "no synctargets", err: &NoSyncTargetsError{LocationWorkspace: logicalcluster.New("root:locations")}},
+		{name: "canceled", err: fmt.Errorf("failed: %w", context.Canceled)},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.retryable, IsRetryableError(tt.err))
+		})
+	}
+}
+
+func TestRunWithRetries(t *testing.T) {
+	requests := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	var callbacks []callbackPayload
+	callbackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload callbackPayload
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		callbacks = append(callbacks, payload)
+	}))
+	defer callbackServer.Close()
+
+	streams, _, _, errOut := genericclioptions.NewTestIOStreams()
+	opts := NewBindComputeOptions(streams)
+	opts.KubectlOverrides.ClusterInfo.Server = server.URL + "/clusters/root:org"
+	opts.KubectlOverrides.ClusterInfo.InsecureSkipTLSVerify = true
+	opts.KubectlOverrides.AuthInfo.Token = "token"
+	opts.Attempts = 3
+	opts.AttemptDelay = 0
+	opts.CallbackURL = callbackServer.URL
+	require.NoError(t, opts.Complete([]string{"root:mylocations"}))
+	require.NoError(t, opts.Validate())
+
+	err := opts.RunWithRetries(context.Background())
+	require.True(t, apierrors.IsServiceUnavailable(err), "unexpected error %v", err)
+	require.Greater(t, requests, 2)
+	require.Equal(t, 2, strings.Count(errOut.String(), "failed, retrying in 0s"))
+	require.Len(t, callbacks, 1, "only the last attempt is reported")
+	require.Equal(t, "failed", callbacks[0].Status)
+}
+
+// fakeClusterClient returns the same fake client for every cluster.
+type fakeClusterClient struct {
+	*fakeclient.Clientset
+}
+
+func (c fakeClusterClient) Cluster(logicalcluster.Name) kcpclient.Interface {
+	return c.Clientset
+}
+
+func TestPrintLocationWorkspaceType(t *testing.T) {
+	workspace := &tenancyv1beta1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{Name: "mylocations"},
+		Spec: tenancyv1beta1.WorkspaceSpec{
+			Type: tenancyv1alpha1.ClusterWorkspaceTypeReference{Name: "universal", Path: "root"},
+		},
+	}
+
+	var out bytes.Buffer
+	opts := NewBindComputeOptions(genericclioptions.IOStreams{Out: &out, ErrOut: &bytes.Buffer{}})
+	opts.LocationWorkspace = logicalcluster.New("root:mylocations")
+	require.NoError(t, opts.printLocationWorkspaceType(context.Background(), fakeClusterClient{fakeclient.NewSimpleClientset(workspace)}))
+	require.Equal(t, "location workspace root:mylocations: type root:universal\n", out.String())
+
+	out.Reset()
+	opts.LocationWorkspace = logicalcluster.New("root:missing")
+	require.NoError(t, opts.printLocationWorkspaceType(context.Background(), fakeClusterClient{fakeclient.NewSimpleClientset()}))
+	require.Contains(t, out.String(), "location workspace root:missing: type unknown")
+}
+
+func TestReconcileLoop(t *testing.T) {
+	client := fakeclient.NewSimpleClientset(newSyncTarget("cluster-1", "root:compute:kubernetes"))
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.LocationWorkspace = logicalcluster.New("root:locations")
+	opts.Refresh = true
+	opts.ResyncInterval = time.Millisecond * 100
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		require.NoError(t, opts.reconcileLoop(ctx, client, client))
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	bindingExists := func(name string) func() bool {
+		return func() bool {
+			_, err := client.ApisV1alpha1().APIBindings().Get(ctx, name, metav1.GetOptions{})
+			return err == nil
+		}
+	}
+	require.Eventually(t, bindingExists(apiBindingName(logicalcluster.New("root:compute"), "kubernetes")), wait.ForeverTestTimeout, time.Millisecond*10)
+
+	_, err := client.WorkloadV1alpha1().SyncTargets().Create(ctx, newSyncTarget("cluster-2", "root:myapis:custom"), metav1.CreateOptions{})
+	require.NoError(t, err)
+	require.Eventually(t, bindingExists(apiBindingName(logicalcluster.New("root:myapis"), "custom")), wait.ForeverTestTimeout, time.Millisecond*10,
+		"a binding should be created for the export of the new synctarget")
+}
+
+func TestReconcileLoopTerminalError(t *testing.T) {
+	forbidden := fakeclient.NewSimpleClientset()
+	forbidden.PrependReactor("list", "synctargets", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(workloadv1alpha1.Resource("synctargets"), "", errors.New("no access"))
+	})
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.LocationWorkspace = logicalcluster.New("root:locations")
+	opts.Refresh = true
+	opts.ResyncInterval = time.Millisecond * 10
+	err := opts.reconcileLoop(context.Background(), forbidden, forbidden)
+	require.True(t, apierrors.IsForbidden(err), "unexpected error %v", err)
+
+	// an APIExport that does not exist is not retried either.
+	binding := newAPIBinding(apiBindingName(logicalcluster.New("root:compute"), "kubernetes"), "root:compute", "kubernetes")
+	conditions.MarkFalse(binding, apisv1alpha1.APIExportValid, apisv1alpha1.APIExportNotFoundReason, conditionsv1alpha1.ConditionSeverityError, "not found")
+	client := fakeclient.NewSimpleClientset(newSyncTarget("cluster-1", "root:compute:kubernetes"), binding)
+	require.ErrorContains(t, opts.reconcileLoop(context.Background(), client, client), "APIExportValid is False with reason APIExportNotFound")
+
+	// without synctargets, the reconciliation goes on until the context is done.
+	errOut := &bytes.Buffer{}
+	opts.IOStreams = genericclioptions.IOStreams{Out: &bytes.Buffer{}, ErrOut: errOut}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	empty := fakeclient.NewSimpleClientset()
+	require.NoError(t, opts.reconcileLoop(ctx, empty, empty))
+	require.Contains(t, errOut.String(), "failed to reconcile APIBindings")
+}
+
+func TestPrintSelectorPreview(t *testing.T) {
+	newLocation := func(name string, labels map[string]string) *schedulingv1alpha1.Location {
+		return &schedulingv1alpha1.Location{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+			Spec: schedulingv1alpha1.LocationSpec{
+				Resource: schedulingv1alpha1.GroupVersionResource{Group: "workload.kcp.dev", Version: "v1alpha1", Resource: "synctargets"},
+			},
+		}
+	}
+	client := fakeclient.NewSimpleClientset(
+		newLocation("east", map[string]string{"region": "us-east1"}),
+		newLocation("west", map[string]string{"region": "us-west1"}),
+		newLocation("gpu", map[string]string{"region": "us-west1", "gpu": "true"}),
+	)
+
+	var out bytes.Buffer
+	opts := NewBindComputeOptions(genericclioptions.IOStreams{Out: &out, ErrOut: &bytes.Buffer{}})
+	opts.LocationWorkspace = logicalcluster.New("root:locations")
+	opts.LocationSelectorsStrings = []string{"region=us-east1", "gpu"}
+	for _, s := range opts.LocationSelectorsStrings {
+		selector, err := metav1.ParseToLabelSelector(s)
+		require.NoError(t, err)
+		opts.locationSelectors = append(opts.locationSelectors, *selector)
+	}
+
+	require.NoError(t, opts.printSelectorPreview(context.Background(), client))
+	require.Equal(t, `location selector "region=us-east1" matches 1 location(s): east
+location selector "gpu" matches 1 location(s): gpu
+placement would select from 2 location(s) in workspace root:locations: east,gpu
+`, out.String())
+	require.Empty(t, client.Actions()[1:], "nothing should be created")
+}
+
+func TestPrintOutputsIncludeMatchedLocations(t *testing.T) {
+	locationResource := schedulingv1alpha1.GroupVersionResource{Group: "workload.kcp.dev", Version: "v1alpha1", Resource: "synctargets"}
+	client := fakeclient.NewSimpleClientset(
+		&schedulingv1alpha1.Location{
+			ObjectMeta: metav1.ObjectMeta{Name: "west", Labels: map[string]string{"region": "us-west1"}},
+			Spec:       schedulingv1alpha1.LocationSpec{Resource: locationResource},
+		},
+		&schedulingv1alpha1.Location{
+			ObjectMeta: metav1.ObjectMeta{Name: "east", Labels: map[string]string{"region": "us-east1"}},
+			Spec:       schedulingv1alpha1.LocationSpec{Resource: locationResource},
+		},
+	)
+	placement := &schedulingv1alpha1.Placement{
+		ObjectMeta: metav1.ObjectMeta{Name: "placement-1a2b3c4d"},
+		Spec: schedulingv1alpha1.PlacementSpec{
+			LocationSelectors: []metav1.LabelSelector{{MatchLabels: map[string]string{"region": "us-east1"}}},
+			LocationResource:  locationResource,
+		},
+	}
+
+	var out bytes.Buffer
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.PlacementName = placement.Name
+	opts.Output = "yaml"
+	opts.IncludeMatchedLocations = true
+	require.NoError(t, opts.Validate())
+	require.NoError(t, opts.printOutputs(context.Background(), &out, client, nil, []*schedulingv1alpha1.Placement{placement}))
+	require.Contains(t, out.String(), "kind: Location\nmetadata:\n  labels:\n    region: us-east1\n  name: east\n")
+	require.NotContains(t, out.String(), "name: west")
+
+	opts.Output = ""
+	require.ErrorContains(t, opts.Validate(), "--include-matched-locations requires -o yaml")
+}
+
+func TestClientConfigsDirectURL(t *testing.T) {
+	tests := []struct {
+		name          string
+		server        string
+		directURL     string
+		wantKcpHost   string
+		wantValidate  string
+		wantConfigErr bool
+	}{
+		{name: "front proxy", server: "https://test/clusters/root:org", wantKcpHost: "https://test"},
+		{name: "not a workspace URL", server: "https://test/services/workload", wantConfigErr: true},
+		{name: "direct URL", server: "https://test/services/workload", directURL: "https://test/services/workload/", wantKcpHost: "https://test/services/workload"},
+		{name: "invalid direct URL", server: "https://test/clusters/root:org", directURL: "test/services/workload", wantValidate: "invalid --direct-url"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+			opts.PlacementName = "placement"
+			opts.DirectURL = tt.directURL
+			opts.ClientConfig = clientcmd.NewDefaultClientConfig(clientcmdapi.Config{
+				Clusters:       map[string]*clientcmdapi.Cluster{"test": {Server: tt.server}},
+				Contexts:       map[string]*clientcmdapi.Context{"test": {Cluster: "test"}},
+				CurrentContext: "test",
+			}, &clientcmd.ConfigOverrides{})
+			if tt.wantValidate != "" {
+				require.ErrorContains(t, opts.Validate(), tt.wantValidate)
+				return
+			}
+			require.NoError(t, opts.Validate())
+
+			config, kcpConfig, err := opts.clientConfigs()
+			if tt.wantConfigErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.server, config.Host)
+			require.Equal(t, tt.wantKcpHost, kcpConfig.Host)
+		})
+	}
+}
+
+func TestWriteFailureReport(t *testing.T) {
+	bound := newAPIBinding("kubernetes", "root:compute", "kubernetes")
+	bound.Status.Phase = apisv1alpha1.APIBindingPhaseBound
+	pending := newAPIBinding("custom", "root:myapis", "custom")
+	pending.Status.Phase = apisv1alpha1.APIBindingPhaseBinding
+	pending.Status.ExportPermissionClaims = []apisv1alpha1.PermissionClaim{{GroupResource: apisv1alpha1.GroupResource{Resource: "configmaps"}, All: true}}
+	placement := &schedulingv1alpha1.Placement{ObjectMeta: metav1.ObjectMeta{Name: "placement-1a2b3c4d"}}
+
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.LocationWorkspace = logicalcluster.New("root:locations")
+	opts.APIExports = []string{"root:myapis:custom", "root:compute:kubernetes"}
+	opts.FailureReport = filepath.Join(t.TempDir(), "reports", "report.json")
+	err := opts.writeFailureReport(wait.ErrWaitTimeout, "waiting for readiness", sets.NewString("root:compute:kubernetes", "root:myapis:custom"), []*apisv1alpha1.APIBinding{bound, pending}, placement)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(opts.FailureReport)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+	"error": "timed out waiting for the condition",
+	"phase": "waiting for readiness",
+	"locationWorkspace": "root:locations",
+	"requestedAPIExports": ["root:compute:kubernetes", "root:myapis:custom"],
+	"supportedAPIExports": ["root:compute:kubernetes", "root:myapis:custom"],
+	"apiBindings": [
+		{"name": "kubernetes", "apiExport": "root:compute:kubernetes", "phase": "Bound", "ready": true},
+		{"name": "custom", "apiExport": "root:myapis:custom", "phase": "Binding", "ready": false, "pendingPermissionClaims": ["configmaps"]}
+	],
+	"placement": {"name": "placement-1a2b3c4d", "phase": "", "ready": false}
+}`, string(data))
+}
+
+func TestCompleteNamespacePreset(t *testing.T) {
+	tests := []struct {
+		name              string
+		preset            string
+		namespaceSelector string
+		wantSelector      string
+		wantErr           bool
+	}{
+		{name: "no preset", wantSelector: "<none>"},
+		{name: "preset", preset: "labeled", wantSelector: "kcp.dev/workload=true"},
+		{name: "selector overrides preset", preset: "labeled", namespaceSelector: "env=prod", wantSelector: "env=prod"},
+		{name: "unknown preset", preset: "unknown", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+			opts.Kubeconfig = filepath.Join(t.TempDir(), "kubeconfig")
+			opts.NamespacePreset = tt.preset
+			if tt.namespaceSelector != "" {
+				opts.NamespaceSelectorString = tt.namespaceSelector
+			}
+			err := opts.Complete([]string{"root:mylocations"})
+			if tt.wantErr {
+				require.ErrorContains(t, err, "unknown namespace preset")
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantSelector, metav1.FormatLabelSelector(opts.namespaceSelector))
+		})
+	}
+}
+
+func TestApplyPlacementRetryAfterSuccess(t *testing.T) {
+	newOptions := func(region string) (*BindComputeOptions, *bytes.Buffer) {
+		var out bytes.Buffer
+		opts := NewBindComputeOptions(genericclioptions.IOStreams{Out: &out, ErrOut: &bytes.Buffer{}})
+		opts.PlacementName = "placement-1a2b3c4d"
+		opts.LocationWorkspace = logicalcluster.New("root:locations")
+		opts.namespaceSelector = &metav1.LabelSelector{}
+		opts.locationSelectors = []metav1.LabelSelector{{MatchLabels: map[string]string{"region": region}}}
+		return opts, &out
+	}
+
+	// the first create succeeds on the server, but the response is lost.
+	client := fakeclient.NewSimpleClientset()
+	lost := false
+	client.PrependReactor("create", "placements", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+		if lost {
+			return false, nil, nil
+		}
+		lost = true
+		if err := client.Tracker().Add(action.(clientgotesting.CreateAction).GetObject()); err != nil {
+			return true, nil, err
+		}
+		return true, nil, errors.New("connection reset by peer")
+	})
+
+	opts, out := newOptions("us-east1")
+	_, err := opts.applyPlacement(context.Background(), client)
+	require.ErrorContains(t, err, "connection reset by peer")
+
+	placement, err := opts.applyPlacement(context.Background(), client)
+	require.NoError(t, err)
+	require.NotEmpty(t, placement.Annotations[IdempotencyKeyAnnotationKey])
+	require.Equal(t, "placement placement-1a2b3c4d unchanged.\n", out.String(), "the retry should find the placement of the create that succeeded")
+
+	// another bind with the same placement name but different inputs does not own the placement.
+	opts, out = newOptions("us-west1")
+	_, err = opts.applyPlacement(context.Background(), client)
+	require.True(t, apierrors.IsConflict(err), "unexpected error %v", err)
+	require.ErrorContains(t, err, "it was created by another bind with different inputs")
+	require.Empty(t, out.String())
+}
+
+func TestApplyPlacementReplace(t *testing.T) {
+	newOptions := func(region string) (*BindComputeOptions, *bytes.Buffer) {
+		var out bytes.Buffer
+		opts := NewBindComputeOptions(genericclioptions.IOStreams{In: strings.NewReader("n\n"), Out: &out, ErrOut: &bytes.Buffer{}})
+		opts.PlacementName = "placement-1a2b3c4d"
+		opts.LocationWorkspace = logicalcluster.New("root:locations")
+		opts.namespaceSelector = &metav1.LabelSelector{}
+		opts.locationSelectors = []metav1.LabelSelector{{MatchLabels: map[string]string{"region": region}}}
+		opts.Replace = true
+		return opts, &out
+	}
+	existing, _ := newOptions("us-east1")
+	client := fakeclient.NewSimpleClientset(&schedulingv1alpha1.Placement{
+		ObjectMeta: metav1.ObjectMeta{Name: "placement-1a2b3c4d"},
+		Spec:       existing.placementSpec(),
+	})
+
+	opts, out := newOptions("us-east1")
+	_, err := opts.applyPlacement(context.Background(), client)
+	require.NoError(t, err)
+	require.Equal(t, "placement placement-1a2b3c4d already exists.\n", out.String(), "a placement with the same spec should be kept")
+
+	opts, _ = newOptions("us-west1")
+	_, err = opts.applyPlacement(context.Background(), client)
+	require.EqualError(t, err, "placement placement-1a2b3c4d exists with different selectors, and replacing it was not confirmed")
+
+	opts, out = newOptions("us-west1")
+	opts.Yes = true
+	placement, err := opts.applyPlacement(context.Background(), client)
+	require.NoError(t, err)
+	require.Equal(t, "placement placement-1a2b3c4d replaced.\n", out.String())
+	require.Equal(t, map[string]string{"region": "us-west1"}, placement.Spec.LocationSelectors[0].MatchLabels)
+	var deleted bool
+	for _, action := range client.Actions() {
+		deleted = deleted || action.Matches("delete", "placements")
+	}
+	require.True(t, deleted)
+}
+
+func TestPrintPlacementSpec(t *testing.T) {
+	var out bytes.Buffer
+	opts := NewBindComputeOptions(genericclioptions.IOStreams{Out: &out, ErrOut: &bytes.Buffer{}})
+	opts.PlacementName = "placement-1a2b3c4d"
+	opts.LocationWorkspace = logicalcluster.New("root:locations")
+	opts.PrintPlacementSpec = true
+	opts.namespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"kcp.dev/workload": "true"}}
+	opts.locationSelectors = []metav1.LabelSelector{{MatchLabels: map[string]string{"region": "us-east1"}}}
+
+	_, err := opts.applyPlacement(context.Background(), fakeclient.NewSimpleClientset())
+	require.NoError(t, err)
+	require.Equal(t, `# placement to be applied
+apiVersion: scheduling.kcp.dev/v1alpha1
+kind: Placement
+metadata:
+  name: placement-1a2b3c4d
+spec:
+  locationResource:
+    group: workload.kcp.dev
+    resource: synctargets
+    version: v1alpha1
+  locationSelectors:
+  - matchLabels:
+      region: us-east1
+  locationWorkspace: root:locations
+  namespaceSelector:
+    matchLabels:
+      kcp.dev/workload: "true"
+placement placement-1a2b3c4d created.
+`, out.String())
+}
+
+func TestCompleteProfile(t *testing.T) {
+	profilesFile := filepath.Join(t.TempDir(), "profiles.yaml")
+	require.NoError(t, os.WriteFile(profilesFile, []byte(`profiles:
+  prod:
+    apiExports:
+    - root:myapis:custom
+    namespaceSelector: kcp.dev/workload=true
+    locationSelectors:
+    - env=prod
+    timeout: 2m
+`), 0644))
+
+	tests := []struct {
+		name              string
+		args              []string
+		wantAPIExports    []string
+		wantLocations     []string
+		wantTimeout       time.Duration
+		wantNamespace     string
+		wantErrorContains string
+	}{
+		{
+			name:           "profile values",
+			args:           []string{"--profile=prod"},
+			wantAPIExports: []string{"root:myapis:custom"},
+			wantLocations:  []string{"env=prod"},
+			wantNamespace:  "kcp.dev/workload=true",
+			wantTimeout:    time.Minute * 2,
+		},
+		{
+			name:           "explicit flags override the profile",
+			args:           []string{"--profile=prod", "--timeout=5s", "--location-selectors=env=staging"},
+			wantAPIExports: []string{"root:myapis:custom"},
+			wantLocations:  []string{"env=staging"},
+			wantNamespace:  "kcp.dev/workload=true",
+			wantTimeout:    time.Second * 5,
+		},
+		{
+			name:              "unknown profile",
+			args:              []string{"--profile=dev"},
+			wantErrorContains: `profile "dev" not found`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+			cmd := &cobra.Command{}
+			opts.BindFlags(cmd)
+			require.NoError(t, cmd.ParseFlags(append(tt.args, "--kubeconfig="+filepath.Join(t.TempDir(), "kubeconfig"), "--profiles-file="+profilesFile)))
+
+			err := opts.Complete([]string{"root:mylocations"})
+			if tt.wantErrorContains != "" {
+				require.ErrorContains(t, err, tt.wantErrorContains)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantAPIExports, opts.APIExports)
+			require.Equal(t, tt.wantLocations, opts.LocationSelectorsStrings)
+			require.Equal(t, tt.wantNamespace, metav1.FormatLabelSelector(opts.namespaceSelector))
+			require.Equal(t, tt.wantTimeout, opts.BindWaitTimeout)
+		})
+	}
+}
+
+func TestBatch(t *testing.T) {
+	dir := t.TempDir()
+	kubeconfig := filepath.Join(dir, "kubeconfig")
+	require.NoError(t, clientcmd.WriteToFile(clientcmdapi.Config{
+		Clusters:       map[string]*clientcmdapi.Cluster{"workspace": {Server: "http://127.0.0.1:1/clusters/root:org"}},
+		Contexts:       map[string]*clientcmdapi.Context{"workspace": {Cluster: "workspace"}},
+		CurrentContext: "workspace",
+	}, kubeconfig))
+	batchFile := filepath.Join(dir, "batch.yaml")
+	require.NoError(t, os.WriteFile(batchFile, []byte(`binds:
+- targetWorkspace: root:org:team-a
+  locationWorkspace: root:mylocations
+  apiExports:
+  - root:myapis:custom
+  locationSelectors:
+  - env=prod
+- locationWorkspace: root:otherlocations
+  placementName: other
+`), 0644))
+
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	cmd := &cobra.Command{}
+	opts.BindFlags(cmd)
+	require.NoError(t, cmd.ParseFlags([]string{"--batch=" + batchFile, "--batch-concurrency=2", "--kubeconfig=" + kubeconfig, "--apiexports=root:compute:kubernetes"}))
+	require.ErrorContains(t, opts.Complete([]string{"root:mylocations"}), "a location workspace cannot be specified with --batch")
+
+	require.NoError(t, opts.Complete(nil))
+	require.NoError(t, opts.Validate())
+	require.Len(t, opts.batch, 2)
+	require.Equal(t, logicalcluster.New("root:org:team-a"), opts.batch[0].targetWorkspace)
+	require.Equal(t, logicalcluster.New("root:mylocations"), opts.batch[0].LocationWorkspace)
+	require.Equal(t, []string{"root:myapis:custom"}, opts.batch[0].APIExports)
+	require.Equal(t, []string{"env=prod"}, opts.batch[0].LocationSelectorsStrings)
+	require.True(t, opts.batch[1].targetWorkspace.Empty())
+	require.Equal(t, logicalcluster.New("root:otherlocations"), opts.batch[1].LocationWorkspace)
+	require.Equal(t, []string{"root:compute:kubernetes"}, opts.batch[1].APIExports, "the flags should be the defaults of the bind requests")
+	require.Equal(t, "other", opts.batch[1].PlacementName)
+
+	// the server cannot be reached, so every bind request fails, and all of them are reported.
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	opts.IOStreams = streams
+	require.EqualError(t, opts.Run(context.Background()), "2 of 2 bind request(s) failed")
+	require.Contains(t, out.String(), "bind request 1 (workspace root:org:team-a to location workspace root:mylocations): failed: ")
+	require.Contains(t, out.String(), "bind request 2 (current workspace to location workspace root:otherlocations): failed: ")
+
+	require.NoError(t, os.WriteFile(batchFile, []byte(`binds:
+- targetWorkspace: root:org:team-a
+`), 0644))
+	opts = NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.Batch = batchFile
+	opts.Kubeconfig = kubeconfig
+	require.EqualError(t, opts.Complete(nil), "bind request 1: a location workspace should be specified")
+}
+
+func TestTimeoutFlag(t *testing.T) {
+	tests := []struct {
+		value             string
+		want              time.Duration
+		wantErrorContains string
+	}{
+		{value: "1m30s", want: time.Second * 90},
+		{value: "0", want: 0},
+		{value: "never", want: 0},
+		{value: "Infinite", want: 0},
+		{value: "forever", wantErrorContains: `invalid argument "forever" for "--timeout" flag`},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.value, func(t *testing.T) {
+			opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+			cmd := &cobra.Command{}
+			opts.BindFlags(cmd)
+			require.Equal(t, time.Second*30, opts.BindWaitTimeout)
+
+			err := cmd.ParseFlags([]string{"--timeout=" + tt.value})
+			if tt.wantErrorContains != "" {
+				require.ErrorContains(t, err, tt.wantErrorContains)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, opts.BindWaitTimeout)
+		})
+	}
+}
+
+func TestPrintSelectorDump(t *testing.T) {
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	opts := NewBindComputeOptions(streams)
+	opts.Kubeconfig = filepath.Join(t.TempDir(), "kubeconfig")
+	opts.NamespaceSelectorString = "env in (prod, staging),!legacy"
+	opts.LocationSelectorsStrings = []string{"region notin (us-west1)", "tier=gold"}
+	opts.PlacementName = "placement"
+	require.NoError(t, opts.Complete([]string{"root:mylocations"}))
+
+	require.NoError(t, opts.printSelectorDump())
+	require.Equal(t, `placement placement:
+  namespace selector: env in (prod,staging),!legacy
+  location selector: region notin (us-west1)
+  location selector: tier=gold
+`, out.String())
+}
+
+func TestLabelNamespaces(t *testing.T) {
+	newOptions := func(t *testing.T, namespaceSelector string) *BindComputeOptions {
+		streams, _, _, _ := genericclioptions.NewTestIOStreams()
+		opts := NewBindComputeOptions(streams)
+		opts.Kubeconfig = filepath.Join(t.TempDir(), "kubeconfig")
+		opts.NamespaceSelectorString = namespaceSelector
+		require.NoError(t, opts.Complete([]string{"root:mylocations"}))
+		return opts
+	}
+	namespaceLabels := func(t *testing.T, client *kubefake.Clientset, name string) map[string]string {
+		namespace, err := client.CoreV1().Namespaces().Get(context.Background(), name, metav1.GetOptions{})
+		require.NoError(t, err)
+		return namespace.Labels
+	}
+	newNamespaces := func() *kubefake.Clientset {
+		return kubefake.NewSimpleClientset(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", Labels: map[string]string{"team": "a"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}},
+		)
+	}
+
+	t.Run("given namespaces", func(t *testing.T) {
+		opts := newOptions(t, "kcp.dev/workload=true")
+		opts.LabelMatchingNamespaces = []string{"default"}
+		require.NoError(t, opts.Validate())
+
+		client := newNamespaces()
+		require.NoError(t, opts.labelNamespaces(context.Background(), client))
+		require.Equal(t, map[string]string{"team": "a", "kcp.dev/workload": "true"}, namespaceLabels(t, client, "default"))
+		require.Empty(t, namespaceLabels(t, client, "apps"))
+		require.Equal(t, "labeled 1 namespace(s) with kcp.dev/workload=true.\n", opts.Out.(*bytes.Buffer).String())
+	})
+
+	t.Run("all namespaces", func(t *testing.T) {
+		opts := newOptions(t, "kcp.dev/workload=true")
+		opts.LabelAllNamespaces = true
+
+		client := newNamespaces()
+		require.NoError(t, opts.labelNamespaces(context.Background(), client))
+		require.Equal(t, map[string]string{"kcp.dev/workload": "true"}, namespaceLabels(t, client, "apps"))
+		require.Equal(t, map[string]string{"team": "a", "kcp.dev/workload": "true"}, namespaceLabels(t, client, "default"))
+	})
+
+	t.Run("several placements", func(t *testing.T) {
+		opts := NewBindComputeOptions(genericclioptions.IOStreams{Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}})
+		opts.Placements = []string{"name=gpu,namespace-selector=gpu=true", "name=prod,namespace-selector=env=prod"}
+		opts.LabelMatchingNamespaces = []string{"apps"}
+		opts.Yes = true
+		require.NoError(t, opts.Complete([]string{"root:mylocations"}))
+		require.NoError(t, opts.Validate())
+
+		client := newNamespaces()
+		require.NoError(t, opts.labelNamespaces(context.Background(), client))
+		require.Equal(t, map[string]string{"gpu": "true", "env": "prod"}, namespaceLabels(t, client, "apps"))
+
+		opts = NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+		opts.Placements = []string{"name=gpu,namespace-selector=env=gpu", "name=prod,namespace-selector=env=prod"}
+		opts.LabelAllNamespaces = true
+		opts.Yes = true
+		require.NoError(t, opts.Complete([]string{"root:mylocations"}))
+		require.ErrorContains(t, opts.Validate(), "placements gpu and prod require different values of namespace label env")
+	})
+
+	t.Run("set-based selector", func(t *testing.T) {
+		opts := newOptions(t, "env in (prod)")
+		opts.LabelAllNamespaces = true
+		require.ErrorContains(t, opts.Validate(), "cannot be applied to namespaces, only key=value labels are supported")
+	})
+
+	t.Run("confirmation", func(t *testing.T) {
+		opts := newOptions(t, "kcp.dev/workload=true")
+		opts.LabelMatchingNamespaces = []string{"default", "apps"}
+		opts.In = strings.NewReader("y\n")
+		confirmed, err := opts.confirmLabelNamespaces()
+		require.NoError(t, err)
+		require.True(t, confirmed)
+		require.Equal(t, "Label namespace(s) default, apps in the current workspace with kcp.dev/workload=true? [y/N]: ", opts.Out.(*bytes.Buffer).String())
+
+		opts.In = strings.NewReader("\n")
+		confirmed, err = opts.confirmLabelNamespaces()
+		require.NoError(t, err)
+		require.False(t, confirmed)
+	})
+}
+
+func TestSpinner(t *testing.T) {
+	var out bytes.Buffer
+	s := newSpinner(&out)
+	require.False(t, isTerminal(&out))
+
+	s.tick()
+	require.Empty(t, out.String(), "nothing should be drawn before the first phase")
+
+	s.SetPhase("creating APIBindings")
+	s.tick()
+	s.tick()
+	_, err := fmt.Fprintf(s, "apibinding kubernetes-1a2b3c4d created.\n")
+	require.NoError(t, err)
+	s.SetPhase("waiting for readiness")
+	s.tick()
+
+	s.Start(time.Hour)
+	s.Stop()
+	require.Equal(t, "| Creating APIBindings...\r\033[K/ Creating APIBindings...\r\033[Kapibinding kubernetes-1a2b3c4d created.\n"+
+		"- Waiting for readiness...\r\033[K", out.String())
+}
+
+func TestCompleteEnvironment(t *testing.T) {
+	t.Setenv("KCP_BIND_APIEXPORTS", "root:myapis:custom,root:myapis:other")
+	t.Setenv("KCP_BIND_LOCATION_SELECTORS", "env=prod,region=us-east1")
+	t.Setenv("KCP_BIND_TIMEOUT", "2m")
+
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	cmd := &cobra.Command{}
+	opts.BindFlags(cmd)
+	require.NoError(t, cmd.ParseFlags([]string{"--timeout=5s", "--kubeconfig=" + filepath.Join(t.TempDir(), "kubeconfig")}))
+
+	require.NoError(t, opts.Complete([]string{"root:mylocations"}))
+	require.Equal(t, []string{"root:myapis:custom", "root:myapis:other"}, opts.APIExports)
+	require.Equal(t, []string{"env=prod", "region=us-east1"}, opts.LocationSelectorsStrings)
+	require.Equal(t, time.Second*5, opts.BindWaitTimeout, "explicit flags should take precedence over the environment")
+
+	t.Setenv("KCP_BIND_TIMEOUT", "soon")
+	opts = NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	cmd = &cobra.Command{}
+	opts.BindFlags(cmd)
+	require.NoError(t, cmd.ParseFlags([]string{"--kubeconfig=" + filepath.Join(t.TempDir(), "kubeconfig")}))
+	require.ErrorContains(t, opts.Complete([]string{"root:mylocations"}), `invalid value "soon" of KCP_BIND_TIMEOUT for --timeout`)
+
+	// the environment only provides defaults, it loses to the flags it conflicts with and to --profile.
+	t.Setenv("KCP_BIND_TIMEOUT", "2m")
+	profilesFile := filepath.Join(t.TempDir(), "profiles.yaml")
+	require.NoError(t, os.WriteFile(profilesFile, []byte("profiles:\n  prod:\n    apiExports:\n    - root:compute:kubernetes\n"), 0600))
+	opts = NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	cmd = &cobra.Command{}
+	opts.BindFlags(cmd)
+	require.NoError(t, cmd.ParseFlags([]string{"--all-locations", "--profile=prod", "--profiles-file=" + profilesFile, "--kubeconfig=" + filepath.Join(t.TempDir(), "kubeconfig")}))
+	require.NoError(t, opts.Complete([]string{"root:mylocations"}))
+	require.NoError(t, opts.Validate())
+	require.Equal(t, []string{labels.Everything().String()}, opts.LocationSelectorsStrings)
+	require.Equal(t, []string{"root:compute:kubernetes"}, opts.APIExports)
+	require.Equal(t, time.Minute*2, opts.BindWaitTimeout)
+	require.False(t, opts.flagChanged("timeout"))
+}
+
+func TestValidateAllSelectors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "all namespaces", args: []string{"--all-namespaces"}},
+		{name: "all locations", args: []string{"--all-locations"}},
+		{name: "empty namespace selector", args: []string{"--all-namespaces", "--namespace-selector="}, wantErr: "--all-namespaces and --namespace-selector are mutually exclusive"},
+		{name: "empty location selectors", args: []string{"--all-locations", "--location-selectors="}, wantErr: "--all-locations and --location-selectors are mutually exclusive"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+			cmd := &cobra.Command{}
+			opts.BindFlags(cmd)
+			require.NoError(t, cmd.ParseFlags(append(tt.args, "--kubeconfig="+filepath.Join(t.TempDir(), "kubeconfig"))))
+			require.NoError(t, opts.Complete([]string{"root:mylocations"}))
+			err := opts.Validate()
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestPrintStatus(t *testing.T) {
+	binding := newAPIBinding("custom", "root:myapis", "custom")
+	binding.Status.Phase = apisv1alpha1.APIBindingPhaseBinding
+	binding.Status.ExportPermissionClaims = []apisv1alpha1.PermissionClaim{{GroupResource: apisv1alpha1.GroupResource{Resource: "configmaps"}, All: true}}
+	placement := &schedulingv1alpha1.Placement{ObjectMeta: metav1.ObjectMeta{Name: "placement-1a2b3c4d"}}
+
+	var out bytes.Buffer
+	opts := NewBindComputeOptions(genericclioptions.IOStreams{Out: &out, ErrOut: &bytes.Buffer{}})
+	require.NoError(t, opts.printStatus("[12s] ", []*apisv1alpha1.APIBinding{binding}, placement))
+	for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n") {
+		require.True(t, strings.HasPrefix(line, "[12s] "), "line %q should be prefixed with the elapsed time", line)
+	}
+	require.Contains(t, out.String(), "[12s] apibinding custom is waiting for acceptance of claims: [configmaps]")
+}
+
+func TestParseOwnerReference(t *testing.T) {
+	tests := []struct {
+		name     string
+		ownerRef string
+		want     metav1.OwnerReference
+		wantErr  string
+	}{
+		{
+			name:     "core",
+			ownerRef: "v1/ConfigMap/config/7b1a0c4e-3f43-4e0c-9a4d-3a6e6c0b8f21",
+			want:     metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "config", UID: "7b1a0c4e-3f43-4e0c-9a4d-3a6e6c0b8f21"},
+		},
+		{
+			name:     "group",
+			ownerRef: "example.com/v1alpha1/Widget/my-widget/7b1a0c4e-3f43-4e0c-9a4d-3a6e6c0b8f21",
+			want:     metav1.OwnerReference{APIVersion: "example.com/v1alpha1", Kind: "Widget", Name: "my-widget", UID: "7b1a0c4e-3f43-4e0c-9a4d-3a6e6c0b8f21"},
+		},
+		{name: "missing parts", ownerRef: "v1/ConfigMap/config", wantErr: "must be in the format"},
+		{name: "invalid kind", ownerRef: "v1/configmap/config/7b1a0c4e-3f43-4e0c-9a4d-3a6e6c0b8f21", wantErr: "invalid kind"},
+		{name: "invalid uid", ownerRef: "v1/ConfigMap/config/1234", wantErr: "invalid uid"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ownerReference, err := parseOwnerReference(tt.ownerRef)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, ownerReference)
+		})
+	}
+}
+
+func TestCheckProtectedWorkspace(t *testing.T) {
+	tests := []struct {
+		name            string
+		server          string
+		targetWorkspace string
+		allowRoot       bool
+		wantErr         bool
+	}{
+		{name: "root", server: "https://test/clusters/root", wantErr: true},
+		{name: "root allowed", server: "https://test/clusters/root", allowRoot: true},
+		{name: "team workspace", server: "https://test/clusters/root:org:team"},
+		{name: "root target", server: "https://test/clusters/root:org:team", targetWorkspace: "root", wantErr: true},
+		{name: "unknown current workspace", server: "https://test/services/workload"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+			opts.AllowRoot = tt.allowRoot
+			opts.targetWorkspace = logicalcluster.New(tt.targetWorkspace)
+			opts.ClientConfig = clientcmd.NewDefaultClientConfig(clientcmdapi.Config{
+				Clusters:       map[string]*clientcmdapi.Cluster{"test": {Server: tt.server}},
+				Contexts:       map[string]*clientcmdapi.Context{"test": {Cluster: "test"}},
+				CurrentContext: "test",
+			}, &clientcmd.ConfigOverrides{})
+
+			err := opts.checkProtectedWorkspace()
+			if tt.wantErr {
+				require.ErrorContains(t, err, "refusing to create APIBindings and placement in the protected workspace root")
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestApplyAPIBindingDeterministicOrder(t *testing.T) {
+	exports := sets.NewString("root:compute:kubernetes", "root:myapis:widgets", "root:myapis:gadgets", "root:other:apps", "root:other:batch")
+
+	var firstOutput string
+	for i := 0; i < 10; i++ {
+		var out bytes.Buffer
+		opts := NewBindComputeOptions(genericclioptions.IOStreams{Out: &out, ErrOut: &bytes.Buffer{}})
+		bindings, err := opts.applyAPIBinding(context.Background(), fakeclient.NewSimpleClientset(newAPIBinding("existing", "root:other", "batch")), exports, nil)
+		require.NoError(t, err)
+
+		var names []string
+		for _, binding := range bindings {
+			names = append(names, binding.Name)
+		}
+		require.True(t, sort.StringsAreSorted(names), "bindings should be sorted by name: %v", names)
+
+		var createdNames []string
+		for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n") {
+			createdNames = append(createdNames, strings.Fields(line)[1])
+		}
+		require.True(t, sort.StringsAreSorted(createdNames), "output should follow the binding names: %v", createdNames)
+
+		if i == 0 {
+			firstOutput = out.String()
+			continue
+		}
+		require.Equal(t, firstOutput, out.String())
+	}
+}
+
+// fakeClusterClients returns the fake client of each cluster, and an empty one for other clusters.
+type fakeClusterClients map[string]*fakeclient.Clientset
+
+func (c fakeClusterClients) Cluster(cluster logicalcluster.Name) kcpclient.Interface {
+	if client, ok := c[cluster.String()]; ok {
+		return client
+	}
+	return fakeclient.NewSimpleClientset()
+}
+
+func TestExplain(t *testing.T) {
+	apiExport := &apisv1alpha1.APIExport{
+		ObjectMeta: metav1.ObjectMeta{Name: "kubernetes"},
+		Spec: apisv1alpha1.APIExportSpec{
+			LatestResourceSchemas: []string{"v1.deployments.apps", "v1.missing"},
+		},
+	}
+	resourceSchema := &apisv1alpha1.APIResourceSchema{
+		ObjectMeta: metav1.ObjectMeta{Name: "v1.deployments.apps"},
+		Spec: apisv1alpha1.APIResourceSchemaSpec{
+			Group: "apps",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{Plural: "deployments", Kind: "Deployment"},
+			Scope: apiextensionsv1.NamespaceScoped,
+			Versions: []apisv1alpha1.APIResourceVersion{
+				{Name: "v1"},
+			},
+		},
+	}
+	kcpClient := fakeClusterClients{
+		"root:compute":     fakeclient.NewSimpleClientset(apiExport, resourceSchema),
+		"root:mylocations": fakeclient.NewSimpleClientset(newSyncTarget("cluster-1", "root:compute:kubernetes"), newSyncTarget("cluster-2", "root:other:kubernetes")),
+	}
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	opts := NewBindComputeExplainOptions(streams)
+	opts.APIExport = "root:compute:kubernetes"
+	opts.LocationWorkspace = "root:mylocations"
+	require.NoError(t, opts.explain(context.Background(), kcpClient))
+
+	require.Contains(t, out.String(), "apiexport root:compute:kubernetes offers 2 resource(s):\n")
+	require.Contains(t, out.String(), "  deployments.apps: kind Deployment, Namespaced, versions v1\n")
+	require.Contains(t, out.String(), "  v1.missing: unknown: ")
+	require.Contains(t, out.String(), "1 synctarget(s) in workspace root:mylocations support apiexport root:compute:kubernetes: cluster-1\n")
+}
+
+func TestFindLocationWorkspace(t *testing.T) {
+	newWorkspace := func(name string) *tenancyv1beta1.Workspace {
+		return &tenancyv1beta1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+	clients := fakeClusterClients{
+		"root:org":           fakeclient.NewSimpleClientset(newWorkspace("team"), newWorkspace("locations"), newWorkspace("gpu-locations")),
+		"root:org:team":      fakeclient.NewSimpleClientset(),
+		"root:org:locations": fakeclient.NewSimpleClientset(newSyncTarget("cluster-1", "root:compute:kubernetes")),
+		"root:org:gpu-locations": fakeclient.NewSimpleClientset(
+			newSyncTarget("cluster-2", "root:compute:kubernetes", "root:myapis:gpus"),
+		),
+	}
+
+	tests := []struct {
+		name       string
+		apiExports []string
+		want       string
+		wantErr    string
+	}{
+		{name: "requested export", apiExports: []string{"root:myapis:gpus"}, want: "root:org:gpu-locations"},
+		{name: "ambiguous", wantErr: "root:org:gpu-locations, root:org:locations"},
+		{name: "not found", apiExports: []string{"root:myapis:other"}, wantErr: "no workspace around root:org:team"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+			opts.APIExports = tt.apiExports
+
+			locationWorkspace, err := opts.findLocationWorkspace(context.Background(), clients, logicalcluster.New("root:org:team"))
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, locationWorkspace.String())
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	_, err := opts.findLocationWorkspace(ctx, clients, logicalcluster.New("root:org:team"))
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestChooseLocationWorkspace(t *testing.T) {
+	matches := []string{"root:org:gpu-locations", "root:org:locations"}
+
+	streams, in, out, _ := genericclioptions.NewTestIOStreams()
+	opts := NewBindComputeOptions(streams)
+	in.WriteString("2\n")
+	locationWorkspace, err := opts.chooseLocationWorkspace(matches)
+	require.NoError(t, err)
+	require.Equal(t, "root:org:locations", locationWorkspace.String())
+	require.Contains(t, out.String(), "  1) root:org:gpu-locations\n  2) root:org:locations\n")
+
+	in.WriteString("3\n")
+	_, err = opts.chooseLocationWorkspace(matches)
+	require.ErrorContains(t, err, `invalid choice "3"`)
+
+	_, err = opts.chooseLocationWorkspace(matches)
+	require.ErrorContains(t, err, `invalid choice ""`)
+}
+
+func TestFindAllLocationWorkspaces(t *testing.T) {
+	newWorkspace := func(name string) *tenancyv1beta1.Workspace {
+		return &tenancyv1beta1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+	forbidden := fakeclient.NewSimpleClientset(newSyncTarget("cluster-3", "root:compute:kubernetes"))
+	forbidden.PrependReactor("list", "workspaces", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(tenancyv1beta1.Resource("workspaces"), "", errors.New("no access"))
+	})
+	clients := fakeClusterClients{
+		"root":                        fakeclient.NewSimpleClientset(newWorkspace("org"), newWorkspace("private")),
+		"root:org":                    fakeclient.NewSimpleClientset(newWorkspace("locations"), newWorkspace("team")),
+		"root:org:locations":          fakeclient.NewSimpleClientset(newSyncTarget("cluster-1", "root:compute:kubernetes")),
+		"root:org:team":               fakeclient.NewSimpleClientset(newWorkspace("gpu-locations")),
+		"root:org:team:gpu-locations": fakeclient.NewSimpleClientset(newSyncTarget("cluster-2", "root:compute:kubernetes", "root:myapis:gpus")),
+		"root:private":                forbidden,
+	}
+
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	locationWorkspaces, err := opts.findAllLocationWorkspaces(context.Background(), clients)
+	require.NoError(t, err)
+	require.Equal(t, []string{"root:org:locations", "root:org:team:gpu-locations", "root:private"}, locationWorkspaces)
+
+	opts.APIExports = []string{"root:myapis:gpus"}
+	locationWorkspaces, err = opts.findAllLocationWorkspaces(context.Background(), clients)
+	require.NoError(t, err)
+	require.Equal(t, []string{"root:org:team:gpu-locations"}, locationWorkspaces)
+
+	// the workspaces are only walked in Run, once the options are validated.
+	opts = NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.KubectlOverrides.ClusterInfo.Server = "https://kcp.example.com:6443/clusters/root:org"
+	opts.KubectlOverrides.AuthInfo.Token = "token"
+	opts.PlacementName = "fleet"
+	require.NoError(t, opts.Complete([]string{wildcardLocationWorkspace}))
+	require.True(t, opts.wildcard)
+	require.Empty(t, opts.batch)
+	err = opts.Validate()
+	require.ErrorContains(t, err, "--yes is required with a wildcard location workspace")
+	require.ErrorContains(t, err, "--name cannot be used with a wildcard location workspace")
+
+	opts.PlacementName = ""
+	opts.Yes = true
+	require.NoError(t, opts.Validate())
+	require.NoError(t, opts.bindWildcard(context.Background(), clients))
+	require.Len(t, opts.batch, 3)
+	require.Equal(t, logicalcluster.New("root:org:locations"), opts.batch[0].LocationWorkspace)
+	require.False(t, opts.batch[0].wildcard)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	require.ErrorContains(t, opts.bindWildcard(ctx, clients), "discovering the location workspaces did not complete within --timeout")
+}
+
+func TestWaitForDeletion(t *testing.T) {
+	stuck := &schedulingv1alpha1.Placement{ObjectMeta: metav1.ObjectMeta{Name: "stuck", Finalizers: []string{"scheduling.kcp.dev/placement"}}}
+	client := fakeclient.NewSimpleClientset(stuck)
+
+	opts := NewBindComputeDeleteOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.Timeout = time.Millisecond * 50
+	opts.PollInterval = time.Millisecond * 10
+
+	require.NoError(t, opts.waitForDeletion(context.Background(), client, []string{"gone"}))
+	require.ErrorContains(t, opts.waitForDeletion(context.Background(), client, []string{"gone", "stuck"}),
+		"placement stuck is still being deleted after 50ms, blocked by finalizers: scheduling.kcp.dev/placement")
+}
+
+func TestApplyAPIBindingCompact(t *testing.T) {
+	var out bytes.Buffer
+	opts := NewBindComputeOptions(genericclioptions.IOStreams{Out: &out, ErrOut: &bytes.Buffer{}})
+	opts.Compact = true
+	opts.Prune = true
+
+	client := fakeclient.NewSimpleClientset(newAPIBinding(apiBindingName(logicalcluster.New("root:myapis"), "stale"), "root:myapis", "stale"))
+	_, err := opts.applyAPIBinding(context.Background(), client, sets.NewString("root:compute:kubernetes", "root:myapis:custom"), nil)
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("created 2 apibinding(s): %s, %s\ndeleted 1 apibinding(s): %s\n",
+		apiBindingName(logicalcluster.New("root:myapis"), "custom"),
+		apiBindingName(logicalcluster.New("root:compute"), "kubernetes"),
+		apiBindingName(logicalcluster.New("root:myapis"), "stale"),
+	), out.String())
+}
+
+func TestVerifyExportEndpoints(t *testing.T) {
+	ready := &apisv1alpha1.APIExport{
+		ObjectMeta: metav1.ObjectMeta{Name: "kubernetes"},
+		Status: apisv1alpha1.APIExportStatus{
+			VirtualWorkspaces: []apisv1alpha1.VirtualWorkspace{{URL: "https://test/services/apiexport/root:compute/kubernetes"}},
+		},
+	}
+	conditions.MarkTrue(ready, apisv1alpha1.APIExportVirtualWorkspaceURLsReady)
+	notReady := &apisv1alpha1.APIExport{ObjectMeta: metav1.ObjectMeta{Name: "custom"}}
+
+	var errOut bytes.Buffer
+	opts := NewBindComputeOptions(genericclioptions.IOStreams{Out: &bytes.Buffer{}, ErrOut: &errOut})
+	clients := fakeClusterClients{
+		"root:compute": fakeclient.NewSimpleClientset(ready),
+		"root:myapis":  fakeclient.NewSimpleClientset(notReady),
+	}
+	require.NoError(t, opts.verifyExportEndpoints(context.Background(), clients, sets.NewString("root:compute:kubernetes", "root:myapis:custom", "root:myapis:missing")))
+	require.Equal(t, `Warning: apiexport root:myapis:custom has no ready endpoints, bindings to it might not be served yet
+Warning: cannot verify the endpoints of apiexport root:myapis:missing: apiexports.apis.kcp.dev "missing" not found
+`, errOut.String())
+}
+
+func TestSourceAnnotations(t *testing.T) {
+	tests := []struct {
+		name     string
+		authInfo *clientcmdapi.AuthInfo
+		wantUser string
+	}{
+		{name: "token", authInfo: &clientcmdapi.AuthInfo{Token: "token"}},
+		{name: "basic auth", authInfo: &clientcmdapi.AuthInfo{Username: "alice", Password: "secret"}, wantUser: "alice"},
+		{name: "impersonation", authInfo: &clientcmdapi.AuthInfo{Token: "token", Impersonate: "bob"}, wantUser: "bob"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+			opts.ClientConfig = clientcmd.NewDefaultClientConfig(clientcmdapi.Config{
+				Clusters:       map[string]*clientcmdapi.Cluster{"test": {Server: "https://test/clusters/root:org"}},
+				AuthInfos:      map[string]*clientcmdapi.AuthInfo{"test": tt.authInfo},
+				Contexts:       map[string]*clientcmdapi.Context{"test": {Cluster: "test", AuthInfo: "test"}},
+				CurrentContext: "test",
+			}, &clientcmd.ConfigOverrides{})
+
+			annotations, err := opts.sourceAnnotations()
+			require.NoError(t, err)
+			require.True(t, strings.HasPrefix(annotations[SourceVersionAnnotationKey], "kcp-bind-compute/"))
+			user, found := annotations[SourceUserAnnotationKey]
+			require.Equal(t, tt.wantUser != "", found)
+			require.Equal(t, tt.wantUser, user)
+		})
+	}
+}
+
+func TestPrintTable(t *testing.T) {
+	bound := newAPIBinding("kubernetes", "root:compute", "kubernetes")
+	bound.Status.Phase = apisv1alpha1.APIBindingPhaseBound
+	placement := &schedulingv1alpha1.Placement{
+		ObjectMeta: metav1.ObjectMeta{Name: "placement-1a2b3c4d"},
+		Spec: schedulingv1alpha1.PlacementSpec{
+			NamespaceSelector: &metav1.LabelSelector{},
+			LocationSelectors: []metav1.LabelSelector{{MatchLabels: map[string]string{"region": "us-east1"}}},
+		},
+	}
+	conditions.MarkTrue(placement, schedulingv1alpha1.PlacementReady)
+
+	var out bytes.Buffer
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.PlacementName = placement.Name
+	require.NoError(t, opts.printTable(&out, []*apisv1alpha1.APIBinding{bound}, placement))
+	require.Equal(t, `NAME                 KIND         EXPORT/SELECTORS                              STATUS
+kubernetes           APIBinding   root:compute:kubernetes                       Bound
+placement-1a2b3c4d   Placement    namespaces=<none> locations=region=us-east1   Ready
+`, out.String())
+
+	out.Reset()
+	opts.NoHeaders = true
+	opts.Columns = []string{"status", "name"}
+	require.NoError(t, opts.Validate())
+	require.NoError(t, opts.printTable(&out, []*apisv1alpha1.APIBinding{bound}, placement))
+	require.Equal(t, "Bound   kubernetes\nReady   placement-1a2b3c4d\n", out.String())
+
+	opts.Columns = []string{"age"}
+	require.ErrorContains(t, opts.Validate(), `invalid column "age"`)
+
+	out.Reset()
+	opts.Output = "wide"
+	opts.Columns = []string{"name", "location-workspace", "namespace-selector", "location-resource", "age"}
+	placement.Spec.LocationWorkspace = "root:mylocations"
+	placement.Spec.LocationResource = schedulingv1alpha1.GroupVersionResource{Group: "workload.kcp.dev", Version: "v1alpha1", Resource: "synctargets"}
+	placement.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Minute * 5))
+	require.NoError(t, opts.Validate())
+	require.NoError(t, opts.printTable(&out, []*apisv1alpha1.APIBinding{bound}, placement))
+	require.Equal(t, `kubernetes                                                                               <unknown>
+placement-1a2b3c4d   root:mylocations   <none>   synctargets.v1alpha1.workload.kcp.dev   5m
+`, out.String())
+
+	require.ErrorIs(t, opts.printTable(failingWriter{}, []*apisv1alpha1.APIBinding{bound}, placement), io.ErrClosedPipe)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestTrace(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer secret-token", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"kind":"APIBindingList","apiVersion":"apis.kcp.dev/v1alpha1","items":[]}`))
+		require.NoError(t, err)
+	}))
+	defer server.Close()
+
+	// the requests go through the transport client-go builds from the config, with its bearer token.
+	errOut := &bytes.Buffer{}
+	opts := NewBindComputeOptions(genericclioptions.IOStreams{Out: &bytes.Buffer{}, ErrOut: errOut})
+	opts.KubectlOverrides.ClusterInfo.Server = server.URL + "/clusters/root:org"
+	opts.KubectlOverrides.ClusterInfo.InsecureSkipTLSVerify = true
+	opts.KubectlOverrides.AuthInfo.Token = "secret-token"
+	opts.Trace = true
+	require.NoError(t, opts.Complete([]string{"root:mylocations"}))
+	client, kcpClient, err := opts.newClients()
+	require.NoError(t, err)
+	_, err = client.ApisV1alpha1().APIBindings().List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	_, err = kcpClient.Cluster(logicalcluster.New("root:mylocations")).ApisV1alpha1().APIBindings().List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+
+	require.NotContains(t, errOut.String(), "secret-token")
+	lines := strings.Split(strings.TrimSpace(errOut.String()), "\n")
+	require.Len(t, lines, 2)
+	require.Regexp(t, `^trace: GET `+server.URL+`/clusters/root:org/apis/apis.kcp.dev/v1alpha1/apibindings 200 OK in \S+ \(Authorization: Bearer <redacted>\)$`, lines[0])
+	require.Regexp(t, `^trace: GET `+server.URL+`/clusters/root:mylocations/apis/apis.kcp.dev/v1alpha1/apibindings 200 OK in \S+ \(Authorization: Bearer <redacted>\)$`, lines[1])
+
+	var out bytes.Buffer
+	rt := (&tracer{out: &out}).wrap(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	}))
+	req, err := http.NewRequest(http.MethodPost, "https://kcp.example.com/clusters/root:org/unreachable", nil)
+	require.NoError(t, err)
+	_, err = rt.RoundTrip(req)
+	require.EqualError(t, err, "connection refused")
+	require.Regexp(t, `^trace: POST https://kcp.example.com/clusters/root:org/unreachable error: connection refused in \S+\n$`, out.String())
+
+	require.Equal(t, "<redacted>", redactAuthorization("token"))
+	require.Empty(t, redactAuthorization(""))
+}
+
+func TestPostCallback(t *testing.T) {
+	var received []callbackPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var payload callbackPayload
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		received = append(received, payload)
+		if payload.Status == "failed" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.LocationWorkspace = logicalcluster.New("root:mylocations")
+	opts.PlacementName = "placement-1"
+	opts.CallbackURL = server.URL
+
+	binding := newAPIBinding("kubernetes", "root:compute", "kubernetes")
+	binding.Status.Phase = apisv1alpha1.APIBindingPhaseBound
+	require.NoError(t, opts.postCallback(nil, []*apisv1alpha1.APIBinding{binding}))
+	require.EqualError(t, opts.postCallback(errors.New("timed out"), nil), fmt.Sprintf("callback URL %s responded with 500 Internal Server Error", server.URL))
+
+	require.Equal(t, []callbackPayload{
+		{
+			Status:            "succeeded",
+			LocationWorkspace: "root:mylocations",
+			Placements:        []string{"placement-1"},
+			APIBindings:       []apiBindingReport{{Name: "kubernetes", APIExport: "root:compute:kubernetes", Phase: "Bound", Ready: true}},
+		},
+		{
+			Status:            "failed",
+			Error:             "timed out",
+			LocationWorkspace: "root:mylocations",
+			Placements:        []string{"placement-1"},
+			APIBindings:       []apiBindingReport{},
+		},
+	}, received)
+
+	opts.CallbackURL = "ftp://example.com"
+	require.ErrorContains(t, opts.Validate(), `--callback-url "ftp://example.com" must be an http or https URL`)
+}
+
+func TestPrintResult(t *testing.T) {
+	streams, _, _, errOut := genericclioptions.NewTestIOStreams()
+	opts := NewBindComputeOptions(streams)
+	opts.Output = "json"
+	opts.LocationWorkspace = logicalcluster.New("root:mylocations")
+	opts.result = &BindComputeResult{LocationWorkspace: "root:mylocations", Warnings: []string{}}
+	require.NoError(t, opts.warn("apiexport %s has no ready endpoints", "root:compute:kubernetes"))
+	require.Equal(t, "Warning: apiexport root:compute:kubernetes has no ready endpoints\n", errOut.String())
+
+	binding := newAPIBinding("kubernetes", "root:compute", "kubernetes")
+	binding.Status.Phase = apisv1alpha1.APIBindingPhaseBound
+	placement := &schedulingv1alpha1.Placement{ObjectMeta: metav1.ObjectMeta{Name: "placement-1"}}
+	conditions.MarkTrue(placement, schedulingv1alpha1.PlacementReady)
+
+	var out bytes.Buffer
+	require.NoError(t, opts.printOutputs(context.Background(), &out, nil, []*apisv1alpha1.APIBinding{binding}, []*schedulingv1alpha1.Placement{placement}))
+	require.Empty(t, out.String(), "the result is printed once the run is reported")
+
+	opts.observeResult([]*apisv1alpha1.APIBinding{binding}, []*schedulingv1alpha1.Placement{placement})
+	require.NoError(t, opts.printResult(&out, opts.result))
+	var result BindComputeResult
+	require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+	require.Equal(t, BindComputeResult{
+		LocationWorkspace: "root:mylocations",
+		Placements:        []BindComputeRef{{Name: "placement-1"}},
+		APIBindings:       []BindComputeRef{{Name: "kubernetes", APIExport: "root:compute:kubernetes"}},
+		Ready:             true,
+		Warnings:          []string{"apiexport root:compute:kubernetes has no ready endpoints"},
+	}, result)
+
+	opts.observeResult([]*apisv1alpha1.APIBinding{binding}, []*schedulingv1alpha1.Placement{{ObjectMeta: metav1.ObjectMeta{Name: "placement-1"}}})
+	require.False(t, opts.result.Ready)
+}
+
+func TestRunResultOnFailure(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	opts := NewBindComputeOptions(streams)
+	opts.KubectlOverrides.ClusterInfo.Server = server.URL + "/clusters/root:org"
+	opts.KubectlOverrides.ClusterInfo.InsecureSkipTLSVerify = true
+	opts.KubectlOverrides.AuthInfo.Token = "token"
+	opts.Output = "json"
+	opts.Attempts = 2
+	opts.AttemptDelay = 0
+	require.NoError(t, opts.Complete([]string{"root:mylocations"}))
+	require.NoError(t, opts.Validate())
+
+	require.Error(t, opts.RunWithRetries(context.Background()))
+	var result BindComputeResult
+	decoder := json.NewDecoder(out)
+	require.NoError(t, decoder.Decode(&result), "the result is printed on failure")
+	require.False(t, decoder.More(), "only the last attempt is printed")
+	require.False(t, result.Ready)
+	require.Equal(t, "root:mylocations", result.LocationWorkspace)
+	require.NotEmpty(t, result.Error)
+	require.Empty(t, result.Placements)
+}
+
+func TestExistingPlacements(t *testing.T) {
+	client := fakeclient.NewSimpleClientset(&schedulingv1alpha1.Placement{ObjectMeta: metav1.ObjectMeta{Name: "gpu"}})
+
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.PlacementName = "gpu"
+	existing, err := opts.existingPlacements(context.Background(), client)
+	require.NoError(t, err)
+	require.Equal(t, []string{"gpu"}, existing)
+
+	opts.placements = []placementOptions{{name: "gpu"}, {name: "cpu"}}
+	existing, err = opts.existingPlacements(context.Background(), client)
+	require.NoError(t, err)
+	require.Equal(t, []string{"gpu"}, existing)
+
+	opts.IfNotExists = true
+	opts.Replace = true
+	require.ErrorContains(t, opts.Validate(), "--if-not-exists and --replace are mutually exclusive")
+}
+
+func TestDumpDebugObjects(t *testing.T) {
+	binding := newAPIBinding("kubernetes", "root:compute", "kubernetes")
+	binding.Annotations = map[string]string{"example.com/api-token": "s3cr3t", "example.com/team": "a"}
+	binding.Status.Phase = apisv1alpha1.APIBindingPhaseBinding
+	client := fakeclient.NewSimpleClientset(binding, newAPIBinding("other", "root:myapis", "other"))
+	location := &schedulingv1alpha1.Location{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+	locationClient := fakeclient.NewSimpleClientset(newSyncTarget("cluster-1", "root:compute:kubernetes"), location)
+
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.PlacementName = "placement-1"
+	opts.LocationWorkspace = logicalcluster.New("root:mylocations")
+
+	var buf bytes.Buffer
+	opts.dumpDebugObjects(context.Background(), &buf, client, locationClient, sets.NewString("root:compute:kubernetes"))
+	dump := buf.String()
+	require.Contains(t, dump, "# placement placement-1 does not exist\n")
+	require.Contains(t, dump, "kind: APIBinding\n")
+	require.Contains(t, dump, "phase: Binding\n")
+	require.Contains(t, dump, "example.com/api-token: <redacted>\n")
+	require.Contains(t, dump, "example.com/team: a\n")
+	require.NotContains(t, dump, "s3cr3t")
+	require.NotContains(t, dump, "name: other\n")
+	require.Contains(t, dump, "kind: SyncTarget\n")
+	require.Contains(t, dump, "kind: Location\n")
+}
+
+func TestBindingNameStrategy(t *testing.T) {
+	exports := sets.NewString("root:compute:kubernetes", "root:myapis:custom")
+	tests := []struct {
+		strategy string
+		exports  sets.String
+		want     []string
+		wantErr  string
+	}{
+		{strategy: "hash", exports: exports, want: []string{
+			apiBindingName(logicalcluster.New("root:myapis"), "custom"),
+			apiBindingName(logicalcluster.New("root:compute"), "kubernetes"),
+		}},
+		{strategy: "export-name", exports: exports, want: []string{"custom", "kubernetes"}},
+		{strategy: "export-name-workspace", exports: exports, want: []string{"custom-root-myapis", "kubernetes-root-compute"}},
+		{strategy: "export-name", exports: sets.NewString("root:compute:kubernetes", "root:other:kubernetes"),
+			wantErr: "apiexports root:compute:kubernetes and root:other:kubernetes would both be bound by apibinding kubernetes with --binding-name-strategy=export-name"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.strategy, func(t *testing.T) {
+			opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+			opts.BindingNameStrategy = tt.strategy
+
+			client := fakeclient.NewSimpleClientset()
+			bindings, err := opts.applyAPIBinding(context.Background(), client, tt.exports, nil)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			var names []string
+			for _, binding := range bindings {
+				names = append(names, binding.Name)
+			}
+			require.Equal(t, tt.want, names)
+		})
+	}
+
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.BindingNameStrategy = "random"
+	require.ErrorContains(t, opts.Validate(), `invalid value "random" for --binding-name-strategy`)
+}
+
+func TestCompleteTokenOnly(t *testing.T) {
+	// the kubeconfig files are not loaded, so a broken one does not matter.
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	require.NoError(t, os.WriteFile(kubeconfig, []byte("not a kubeconfig"), 0600))
+	t.Setenv("KUBECONFIG", kubeconfig)
+
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.KubectlOverrides.ClusterInfo.Server = "https://kcp.example.com:6443/clusters/root:org"
+	opts.KubectlOverrides.ClusterInfo.InsecureSkipTLSVerify = true
+	opts.KubectlOverrides.AuthInfo.Token = "token"
+	require.NoError(t, opts.Complete([]string{"root:mylocations"}))
+	require.NoError(t, opts.Validate())
+
+	config, kcpConfig, err := opts.clientConfigs()
+	require.NoError(t, err)
+	require.Equal(t, "https://kcp.example.com:6443/clusters/root:org", config.Host)
+	require.Equal(t, "https://kcp.example.com:6443", kcpConfig.Host)
+	require.Equal(t, "token", kcpConfig.BearerToken)
+	require.True(t, kcpConfig.Insecure)
+
+	opts = NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.KubectlOverrides.ClusterInfo.Server = "https://kcp.example.com:6443"
+	opts.KubectlOverrides.AuthInfo.Token = "token"
+	require.NoError(t, opts.Complete([]string{"root:mylocations"}))
+	require.ErrorContains(t, opts.Validate(), `--server "https://kcp.example.com:6443" must be the URL of a workspace`)
+}
+
+func TestBindTimings(t *testing.T) {
+	binding := newAPIBinding("binding", "root:myapis", "custom")
+	binding.Status.Phase = apisv1alpha1.APIBindingPhaseBinding
+	bound := binding.DeepCopy()
+	bound.Status.Phase = apisv1alpha1.APIBindingPhaseBound
+
+	timings := &bindTimings{start: time.Now().Add(-time.Second * 2)}
+	timings.observe([]*apisv1alpha1.APIBinding{binding})
+	require.Equal(t, "timings: no apibinding bound, ready after 3s.", timings.String(time.Second*3))
+
+	timings.observe([]*apisv1alpha1.APIBinding{binding, bound})
+	firstBound := timings.firstBound
+	require.GreaterOrEqual(t, firstBound, time.Second*2)
+	timings.start = timings.start.Add(-time.Minute)
+	timings.observe([]*apisv1alpha1.APIBinding{bound})
+	require.Equal(t, firstBound, timings.firstBound, "only the first bound APIBinding is recorded")
+
+	timings.firstBound = time.Millisecond * 1234
+	require.Equal(t, "timings: first apibinding bound after 1.2s, ready after 5.7s.", timings.String(time.Millisecond*5678))
+}
+
+func TestCopySelectorsFrom(t *testing.T) {
+	client := fakeclient.NewSimpleClientset(&schedulingv1alpha1.Placement{
+		ObjectMeta: metav1.ObjectMeta{Name: "gpu"},
+		Spec: schedulingv1alpha1.PlacementSpec{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+			LocationSelectors: []metav1.LabelSelector{{MatchLabels: map[string]string{"gpu": "true"}}, {MatchLabels: map[string]string{"region": "eu"}}},
+		},
+	}, &schedulingv1alpha1.Placement{
+		ObjectMeta: metav1.ObjectMeta{Name: "everywhere"},
+		Spec:       schedulingv1alpha1.PlacementSpec{LocationSelectors: []metav1.LabelSelector{{MatchLabels: map[string]string{"gpu": "true"}}, {}}},
+	})
+	newOptions := func(t *testing.T, args ...string) *BindComputeOptions {
+		opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+		cmd := &cobra.Command{}
+		opts.BindFlags(cmd)
+		require.NoError(t, cmd.ParseFlags(append(args, "--kubeconfig="+filepath.Join(t.TempDir(), "kubeconfig"))))
+		require.NoError(t, opts.Complete([]string{"root:mylocations"}))
+		require.NoError(t, opts.Validate())
+		return opts
+	}
+
+	// the placement is only read in Run, the selectors of the flags are completed until then.
+	opts := newOptions(t, "--selectors-from=gpu")
+	defaultName := opts.PlacementName
+	require.NoError(t, opts.copySelectorsFrom(context.Background(), client))
+	require.Equal(t, "team=a", opts.NamespaceSelectorString)
+	require.Equal(t, []string{"gpu=true", "region=eu"}, opts.LocationSelectorsStrings)
+	require.Equal(t, map[string]string{"team": "a"}, opts.placementSpec().NamespaceSelector.MatchLabels)
+	require.Len(t, opts.placementSpec().LocationSelectors, 2)
+	require.NotEqual(t, defaultName, opts.PlacementName, "the placement is named after the copied selectors")
+
+	opts = newOptions(t, "--selectors-from=gpu", "--location-selectors=env=prod", "--name=copy")
+	require.NoError(t, opts.copySelectorsFrom(context.Background(), client))
+	require.Equal(t, "team=a", opts.NamespaceSelectorString)
+	require.Equal(t, []string{"env=prod"}, opts.LocationSelectorsStrings, "the selector flags override the copied selectors")
+	require.Equal(t, "copy", opts.PlacementName)
+
+	opts = newOptions(t, "--selectors-from=everywhere")
+	require.NoError(t, opts.copySelectorsFrom(context.Background(), client))
+	require.Equal(t, []string{labels.Everything().String()}, opts.LocationSelectorsStrings)
+
+	opts.SelectorsFrom = "missing"
+	require.ErrorContains(t, opts.copySelectorsFrom(context.Background(), client), "placement missing to copy the selectors from not found in the current workspace")
+}
+
+func TestValidateFieldManager(t *testing.T) {
+	tests := []struct {
+		name              string
+		args              []string
+		wantErrorContains string
+	}{
+		{name: "default", args: []string{"--server-side-apply"}},
+		{name: "custom", args: []string{"--server-side-apply", "--field-manager=my-controller"}},
+		{name: "without server-side apply", args: []string{"--field-manager=my-controller"}, wantErrorContains: "--field-manager requires --server-side-apply"},
+		{name: "empty", args: []string{"--server-side-apply", "--field-manager="}, wantErrorContains: "--field-manager must be between 1 and 128 characters long"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+			cmd := &cobra.Command{}
+			opts.BindFlags(cmd)
+			require.NoError(t, cmd.ParseFlags(append(tt.args, "--kubeconfig="+filepath.Join(t.TempDir(), "kubeconfig"))))
+			require.NoError(t, opts.Complete([]string{"root:mylocations"}))
+
+			err := opts.Validate()
+			if tt.wantErrorContains != "" {
+				require.ErrorContains(t, err, tt.wantErrorContains)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestPrintPlacementRefs(t *testing.T) {
+	placements := []*schedulingv1alpha1.Placement{
+		{ObjectMeta: metav1.ObjectMeta{Name: "gpu"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "cpu"}},
+	}
+
+	out := &bytes.Buffer{}
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.PrintRef = true
+	opts.targetWorkspace = logicalcluster.New("root:org:team")
+	require.NoError(t, opts.printOutputs(context.Background(), out, fakeclient.NewSimpleClientset(), nil, placements))
+	require.Equal(t, "root:org:team/placements/gpu\nroot:org:team/placements/cpu\n", out.String())
+
+	// without --target-workspace, the placements are in the current workspace.
+	out.Reset()
+	opts = NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.KubectlOverrides.ClusterInfo.Server = "https://kcp.example.com:6443/clusters/root:org"
+	opts.KubectlOverrides.AuthInfo.Token = "token"
+	opts.PrintRef = true
+	require.NoError(t, opts.Complete([]string{"root:mylocations"}))
+	require.NoError(t, opts.Validate())
+	require.NoError(t, opts.printPlacementRefs(out, []string{"gpu"}))
+	require.Equal(t, "root:org/placements/gpu\n", out.String())
+
+	opts.Output = "yaml"
+	require.ErrorContains(t, opts.Validate(), "--print-ref cannot be used with -o yaml")
+}
+
+func TestCompleteValidate(t *testing.T) {
+	t.Setenv("KCP_BIND_LOCATION_SELECTORS", "env=prod")
+	newOptions := func(t *testing.T) *BindComputeOptions {
+		opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+		cmd := &cobra.Command{}
+		opts.BindValidateFlags(cmd)
+		require.NoError(t, cmd.ParseFlags([]string{"--kubeconfig=" + filepath.Join(t.TempDir(), "kubeconfig")}))
+		return opts
+	}
+
+	opts := newOptions(t)
+	require.NoError(t, opts.CompleteValidate([]string{"root:mylocations"}))
+	require.Equal(t, []string{"env=prod"}, opts.LocationSelectorsStrings, "the environment defaults apply to validate as well")
+
+	opts = newOptions(t)
+	require.EqualError(t, opts.CompleteValidate([]string{wildcardLocationWorkspace}), "a wildcard location workspace cannot be validated, specify a single location workspace")
+}
+
+func TestValidateIssues(t *testing.T) {
+	opts := NewBindComputeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	opts.LocationWorkspace = logicalcluster.New("root:mylocations")
+
+	issues := opts.validateIssues(context.Background(), fakeClusterClients{"root": fakeclient.NewSimpleClientset()})
+	require.Equal(t, []string{"location workspace root:mylocations not found"}, issues)
+
+	forbidden := fakeclient.NewSimpleClientset()
+	forbidden.PrependReactor("get", "workspaces", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(tenancyv1beta1.Resource("workspaces"), "mylocations", errors.New("no access"))
+	})
+	issues = opts.validateIssues(context.Background(), fakeClusterClients{"root": forbidden})
+	require.Len(t, issues, 1, "the checks of the location workspace are skipped when it cannot be read")
+	require.Contains(t, issues[0], "failed to get location workspace root:mylocations")
+	require.Contains(t, issues[0], "forbidden")
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tracer logs each request made through its round tripper to out, for debugging how requests are routed to the
+// workspaces. Credentials are never logged: only the scheme of the Authorization header is shown.
+type tracer struct {
+	lock sync.Mutex
+	out  io.Writer
+}
+
+// wrap returns a round tripper logging the requests made through rt.
+func (t *tracer) wrap(rt http.RoundTripper) http.RoundTripper {
+	return &traceRoundTripper{tracer: t, delegate: rt}
+}
+
+type traceRoundTripper struct {
+	tracer   *tracer
+	delegate http.RoundTripper
+}
+
+func (rt *traceRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := rt.delegate.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	status := ""
+	if err != nil {
+		status = "error: " + err.Error()
+	} else {
+		status = resp.Status
+	}
+	line := fmt.Sprintf("trace: %s %s %s in %s", req.Method, req.URL, status, elapsed)
+	if auth := redactAuthorization(req.Header.Get("Authorization")); len(auth) > 0 {
+		line += " (Authorization: " + auth + ")"
+	}
+
+	rt.tracer.lock.Lock()
+	defer rt.tracer.lock.Unlock()
+	// tracing is best effort, it must not fail the request.
+	fmt.Fprintln(rt.tracer.out, line) //nolint:errcheck
+	return resp, err
+}
+
+// redactAuthorization returns the scheme of the Authorization header value with its credentials redacted, e.g.
+// "Bearer <redacted>".
+func redactAuthorization(value string) string {
+	if len(value) == 0 {
+		return ""
+	}
+	if scheme, _, found := strings.Cut(value, " "); found {
+		return scheme + " <redacted>"
+	}
+	return "<redacted>"
+}
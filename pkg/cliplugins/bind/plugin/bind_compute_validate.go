@@ -0,0 +1,315 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/cliplugins/helpers"
+)
+
+// isAbsoluteWorkspacePath returns whether the workspace path starts at the root or at a system workspace, rather than
+// being relative to the current workspace.
+func isAbsoluteWorkspacePath(path string) bool {
+	return path == tenancyv1alpha1.RootCluster.String() || strings.HasPrefix(path, tenancyv1alpha1.RootCluster.String()+":") || strings.HasPrefix(path, "system:")
+}
+
+// validateSelectorOperators checks that the match expressions of the selector only use the operators supported by
+// kcp scheduling, with values only for In and NotIn.
+func validateSelectorOperators(selector *metav1.LabelSelector) error {
+	var errs []error
+	for _, requirement := range selector.MatchExpressions {
+		switch requirement.Operator {
+		case metav1.LabelSelectorOpIn, metav1.LabelSelectorOpNotIn:
+			if len(requirement.Values) == 0 {
+				errs = append(errs, fmt.Errorf("operator %s on key %q requires at least one value", requirement.Operator, requirement.Key))
+			}
+		case metav1.LabelSelectorOpExists, metav1.LabelSelectorOpDoesNotExist:
+			if len(requirement.Values) > 0 {
+				errs = append(errs, fmt.Errorf("operator %s on key %q does not take values, got %s", requirement.Operator, requirement.Key, strings.Join(requirement.Values, ",")))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("operator %q on key %q is not supported, must be one of In, NotIn, Exists, DoesNotExist", requirement.Operator, requirement.Key))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// Validate validates the BindOptions are complete and usable.
+func (o *BindComputeOptions) Validate() error {
+	if len(o.batch) > 0 || o.wildcard {
+		return o.validateBatch()
+	}
+
+	var errs []error
+
+	if err := o.Options.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if o.tokenOnly() {
+		// without a kubeconfig, the server must carry the workspace.
+		server := o.KubectlOverrides.ClusterInfo.Server
+		if u, err := url.Parse(server); err != nil || (u.Scheme != "https" && u.Scheme != "http") || len(u.Host) == 0 {
+			errs = append(errs, fmt.Errorf("--server %q must be an http or https URL", server))
+		} else if _, _, err := helpers.ParseClusterURL(server); err != nil {
+			errs = append(errs, fmt.Errorf("--server %q must be the URL of a workspace, like https://kcp.example.com:6443/clusters/root:org", server))
+		}
+	}
+
+	if msgs := validation.IsDNS1123Subdomain(o.PlacementName); len(msgs) > 0 {
+		errs = append(errs, fmt.Errorf("invalid placement name %q: %s", o.PlacementName, strings.Join(msgs, ", ")))
+	}
+
+	placementNames := sets.NewString()
+	for _, placement := range o.placements {
+		if msgs := validation.IsDNS1123Subdomain(placement.name); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("invalid placement name %q: %s", placement.name, strings.Join(msgs, ", ")))
+		}
+		if placementNames.Has(placement.name) {
+			errs = append(errs, fmt.Errorf("placement %s is given more than once with --placement", placement.name))
+		}
+		placementNames.Insert(placement.name)
+	}
+
+	if len(o.placements) > 0 && o.flagChanged("name") {
+		errs = append(errs, errors.New("--name cannot be combined with --placement, name the placements in the tuples instead"))
+	}
+
+	if len(o.placements) > 1 && o.Output == "name-vars" {
+		errs = append(errs, errors.New("-o name-vars cannot be used with several --placement"))
+	}
+
+	if o.AllNamespaces && o.flagChanged("namespace-selector") {
+		errs = append(errs, errors.New("--all-namespaces and --namespace-selector are mutually exclusive"))
+	}
+
+	if o.AllNamespaces && len(o.NamespacePreset) > 0 {
+		errs = append(errs, errors.New("--all-namespaces and --namespace-preset are mutually exclusive"))
+	}
+
+	if o.AllLocations && o.flagChanged("location-selectors") {
+		errs = append(errs, errors.New("--all-locations and --location-selectors are mutually exclusive"))
+	}
+
+	if o.WaitBindingsOnly && o.WaitPlacementOnly {
+		errs = append(errs, errors.New("--wait-bindings-only and --wait-placement-only are mutually exclusive"))
+	}
+
+	for _, conditionType := range o.RequiredConditions {
+		if len(strings.TrimSpace(conditionType)) == 0 {
+			errs = append(errs, errors.New("--require-condition cannot be empty"))
+		}
+	}
+
+	if o.QPS <= 0 {
+		errs = append(errs, errors.New("--qps must be positive"))
+	}
+
+	if o.Burst <= 0 {
+		errs = append(errs, errors.New("--burst must be positive"))
+	}
+
+	if len(o.DirectURL) > 0 {
+		if u, err := url.Parse(o.DirectURL); err != nil {
+			errs = append(errs, fmt.Errorf("invalid --direct-url %q: %w", o.DirectURL, err))
+		} else if (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 || len(u.RawQuery) > 0 || len(u.Fragment) > 0 {
+			errs = append(errs, fmt.Errorf("invalid --direct-url %q: must be an http or https URL without query or fragment", o.DirectURL))
+		}
+	}
+
+	if o.Attempts < 1 {
+		errs = append(errs, errors.New("--attempts must be at least 1"))
+	}
+
+	if o.AttemptDelay < 0 {
+		errs = append(errs, errors.New("--attempt-delay cannot be negative"))
+	}
+
+	if o.Deadline < 0 {
+		errs = append(errs, errors.New("--deadline cannot be negative"))
+	}
+
+	if len(o.CallbackURL) > 0 {
+		if u, err := url.Parse(o.CallbackURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+			errs = append(errs, fmt.Errorf("--callback-url %q must be an http or https URL", o.CallbackURL))
+		}
+	}
+
+	if o.PollInterval <= 0 {
+		errs = append(errs, errors.New("--poll-interval must be positive"))
+	} else if o.BindWaitTimeout > 0 && o.PollInterval >= o.BindWaitTimeout {
+		errs = append(errs, errors.New("--poll-interval must be less than --timeout"))
+	}
+
+	if len(o.KubernetesVersion) > 0 {
+		if len(o.APIExports) > 0 {
+			errs = append(errs, errors.New("--bind-kubernetes-version cannot be combined with --apiexports"))
+		}
+		if msgs := validation.IsDNS1123Label(o.kubernetesAPIExportName()); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("--bind-kubernetes-version %q does not form a valid APIExport name: %s", o.KubernetesVersion, strings.Join(msgs, ", ")))
+		}
+	}
+
+	if o.PollFactor < 1 {
+		errs = append(errs, errors.New("--poll-factor must be at least 1"))
+	}
+
+	if o.PollMaxInterval < o.PollInterval {
+		errs = append(errs, errors.New("--poll-max-interval cannot be less than --poll-interval"))
+	}
+
+	if o.PollJitter < 0 {
+		errs = append(errs, errors.New("--poll-jitter cannot be negative"))
+	}
+
+	if len(o.AcceptPermissionClaims) > 1 && sets.NewString(o.AcceptPermissionClaims...).Has("all") {
+		errs = append(errs, errors.New("--accept-permission-claims=all cannot be combined with other claims"))
+	}
+
+	if o.ResyncInterval <= 0 {
+		errs = append(errs, errors.New("--resync-interval must be positive"))
+	}
+
+	if len(o.FromSyncTarget) > 0 && len(o.SyncTargetSelector) > 0 {
+		errs = append(errs, errors.New("--from-synctarget and --synctarget-selector are mutually exclusive"))
+	}
+
+	if o.IgnoreMissingExcludes && len(o.ExcludeAPIExports) == 0 {
+		errs = append(errs, errors.New("--ignore-missing-excludes requires --exclude-apiexports"))
+	}
+
+	// a kind form is accepted by the API, but never matches the locations. A singular form is told from the plural
+	// with discovery in Run.
+	if resource := o.locationResource.Resource; resource != strings.ToLower(resource) {
+		errs = append(errs, fmt.Errorf("location resource %q must be the plural lowercase resource name, not the kind, e.g. %s", resource, defaultLocationResource.Resource))
+	} else if msgs := validation.IsDNS1035Label(resource); len(msgs) > 0 {
+		errs = append(errs, fmt.Errorf("invalid location resource %q: %s", resource, strings.Join(msgs, ", ")))
+	}
+
+	if o.OutputStatusOnly && o.NoWaitOnExisting {
+		errs = append(errs, errors.New("--output-status-only and --no-wait-on-existing are mutually exclusive"))
+	}
+
+	if o.IfNotExists {
+		for _, exclusive := range []struct {
+			flag string
+			set  bool
+		}{{"--output-status-only", o.OutputStatusOnly}, {"--no-wait-on-existing", o.NoWaitOnExisting}, {"--replace", o.Replace}} {
+			if exclusive.set {
+				errs = append(errs, fmt.Errorf("--if-not-exists and %s are mutually exclusive", exclusive.flag))
+			}
+		}
+	}
+
+	if o.Replace && o.ServerSideApply {
+		errs = append(errs, errors.New("--replace and --server-side-apply are mutually exclusive, server-side apply updates the placement in place"))
+	}
+
+	if o.flagChanged("field-manager") && !o.ServerSideApply {
+		errs = append(errs, errors.New("--field-manager requires --server-side-apply"))
+	}
+	if o.ServerSideApply && (len(o.FieldManager) == 0 || len(o.FieldManager) > maxFieldManagerLength) {
+		errs = append(errs, fmt.Errorf("--field-manager must be between 1 and %d characters long", maxFieldManagerLength))
+	}
+
+	if o.Replace && o.Kubeconfig == "-" && !o.Yes {
+		errs = append(errs, errors.New("--yes is required with --replace when reading the kubeconfig from stdin"))
+	}
+
+	if _, ok := bindingNameStrategies[o.BindingNameStrategy]; !ok {
+		errs = append(errs, fmt.Errorf("invalid value %q for --binding-name-strategy; valid values are export-name, export-name-workspace, hash", o.BindingNameStrategy))
+	} else if err := o.checkBindingNames(sets.NewString(o.APIExports...)); err != nil {
+		errs = append(errs, err)
+	}
+
+	if o.Prune && !o.Refresh {
+		errs = append(errs, errors.New("--prune requires --refresh"))
+	}
+
+	if o.Output != "" && o.Output != "yaml" && o.Output != "json" && o.Output != "name-vars" && o.Output != "wide" {
+		errs = append(errs, fmt.Errorf("invalid value %q for --output; valid values are json, name-vars, wide, yaml", o.Output))
+	}
+
+	// the references are printed to stdout on their own, to be read by other commands.
+	if o.PrintRef && o.Output != "" && o.Output != "wide" {
+		errs = append(errs, fmt.Errorf("--print-ref cannot be used with -o %s", o.Output))
+	}
+
+	for _, workspace := range o.ProtectedWorkspaces {
+		if !logicalcluster.New(workspace).IsValid() {
+			errs = append(errs, fmt.Errorf("protected workspace %q is not a valid workspace path", workspace))
+		}
+	}
+
+	if len(o.ProfilesFile) > 0 && len(o.Profile) == 0 {
+		errs = append(errs, errors.New("--profiles-file requires --profile"))
+	}
+
+	for _, column := range o.Columns {
+		if !sets.NewString(o.availableTableColumns()...).Has(strings.ToUpper(column)) {
+			errs = append(errs, fmt.Errorf("invalid column %q for --columns; valid columns are %s", column, strings.Join(o.availableTableColumns(), ", ")))
+		}
+	}
+
+	if o.OutputFile != "" && o.Output == "" {
+		errs = append(errs, errors.New("--output-file requires --output"))
+	}
+
+	if o.IncludeMatchedLocations && o.Output != "yaml" {
+		errs = append(errs, errors.New("--include-matched-locations requires -o yaml"))
+	}
+
+	if len(o.LabelMatchingNamespaces) > 0 && o.LabelAllNamespaces {
+		errs = append(errs, errors.New("--label-matching-namespaces and --label-all-namespaces are mutually exclusive"))
+	}
+
+	if o.labelsNamespaces() {
+		for _, po := range o.placementOptions() {
+			if po.namespaceSelector == nil {
+				continue
+			}
+			if len(po.namespaceSelector.MatchExpressions) > 0 || len(po.namespaceSelector.MatchLabels) == 0 {
+				errs = append(errs, fmt.Errorf("namespace selector %s of placement %s cannot be applied to namespaces, only key=value labels are supported",
+					metav1.FormatLabelSelector(po.namespaceSelector), po.PlacementName))
+			}
+		}
+		if _, err := o.namespaceMatchLabels(); err != nil {
+			errs = append(errs, err)
+		}
+		if o.Kubeconfig == "-" && !o.Yes {
+			errs = append(errs, errors.New("--yes is required to label namespaces when reading the kubeconfig from stdin"))
+		}
+	}
+
+	if o.OutputFile != "" && o.Output == "wide" {
+		errs = append(errs, errors.New("--output-file cannot be used with -o wide"))
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package version reports the build of the bind plugin, and the kcp API versions it talks to.
+package version
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	apimachineryversion "k8s.io/apimachinery/pkg/version"
+	"k8s.io/component-base/version"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+)
+
+// Info is the build information of the plugin. The version, commit and build date are set at build time through
+// the k8s.io/component-base/version ldflags, see `make ldflags`.
+type Info struct {
+	apimachineryversion.Info
+
+	// APIGroupVersions are the kcp API group versions the plugin is compatible with.
+	APIGroupVersions []string
+}
+
+// Get returns the build information of the plugin.
+func Get() Info {
+	return Info{
+		Info: version.Get(),
+		APIGroupVersions: []string{
+			apisv1alpha1.SchemeGroupVersion.String(),
+			schedulingv1alpha1.SchemeGroupVersion.String(),
+			workloadv1alpha1.SchemeGroupVersion.String(),
+		},
+	}
+}
+
+// Print prints the build information, one field per line.
+func (i Info) Print(out io.Writer) error {
+	_, err := fmt.Fprintf(out, "version: %s\ngit commit: %s (%s)\nbuild date: %s\ngo version: %s\nplatform: %s\ncompatible APIs: %s\n",
+		valueOrUnknown(i.GitVersion), valueOrUnknown(i.GitCommit), valueOrUnknown(i.GitTreeState), valueOrUnknown(i.BuildDate), i.GoVersion, i.Platform,
+		strings.Join(i.APIGroupVersions, ", "))
+	return err
+}
+
+// valueOrUnknown returns the value, or <unknown> if it was not set at build time.
+func valueOrUnknown(value string) string {
+	if len(value) == 0 {
+		return "<unknown>"
+	}
+	return value
+}
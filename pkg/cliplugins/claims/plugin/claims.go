@@ -20,7 +20,6 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"net/url"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -29,11 +28,8 @@ import (
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/printers"
-	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
 
 	apiv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
-	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
 	"github.com/kcp-dev/kcp/pkg/cliplugins/base"
 	pluginhelpers "github.com/kcp-dev/kcp/pkg/cliplugins/helpers"
 )
@@ -90,7 +86,7 @@ func (g *GetAPIBindingOptions) Run(ctx context.Context) error {
 		return fmt.Errorf("current URL %q does not point to cluster workspace", cfg.Host)
 	}
 
-	kcpClusterClient, err := newKCPClusterClient(g.ClientConfig)
+	_, kcpClusterClient, err := g.KcpClients()
 	if err != nil {
 		return fmt.Errorf("error while creating kcp client %w", err)
 	}
@@ -132,22 +128,6 @@ func (g *GetAPIBindingOptions) Run(ctx context.Context) error {
 	return utilerrors.NewAggregate(allErrors)
 }
 
-func newKCPClusterClient(clientConfig clientcmd.ClientConfig) (kcpclient.ClusterInterface, error) {
-	config, err := clientConfig.ClientConfig()
-	if err != nil {
-		return nil, err
-	}
-	clusterConfig := rest.CopyConfig(config)
-	u, err := url.Parse(config.Host)
-	if err != nil {
-		return nil, err
-	}
-	u.Path = ""
-	clusterConfig.Host = u.String()
-	clusterConfig.UserAgent = rest.DefaultKubernetesUserAgent()
-	return kcpclient.NewClusterForConfig(clusterConfig)
-}
-
 func printHeaders(out io.Writer) error {
 	columnNames := []string{"APIBINDING", "RESOURCE GROUP-VERSION", "STATUS"}
 	_, err := fmt.Fprintf(out, "%s\n", strings.Join(columnNames, "\t"))